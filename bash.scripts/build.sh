@@ -31,7 +31,7 @@ for PLATFORM in "${PLATFORMS[@]}"; do
         OUTPUT_PATH="${BIN_PATH}/${BIN_NAME}"
         echo "🚀 Compiling for ${PLATFORM}/${ARCH}..."
 
-        (cd "$SRC_DIR/gofly-cli" && GOOS=$PLATFORM GOARCH=$ARCH go build -o "$OUTPUT_PATH" -ldflags "-s -w" main.go)
+        (cd "$SRC_DIR/gofly-cli" && GOOS=$PLATFORM GOARCH=$ARCH go build -o "$OUTPUT_PATH" -ldflags "-s -w" .)
 
          if [ $? -eq 0 ]; then
             echo "✅ Compilation finished: ${OUTPUT_PATH}"
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/viewer"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	callSortColumn = 1
+	callSortDesc   = false
+)
+
+// callColumn values are computed with the state locked, as calls change
+// while entries arrive
+type callColumn struct {
+	title string
+	value func(s *viewer.State, c *calls.Call) string
+	less  func(s *viewer.State, a, b *calls.Call) bool
+}
+
+var callColumns = []callColumn{
+	{"Call-ID", func(s *viewer.State, c *calls.Call) string { return c.CallID },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.CallID < b.CallID }},
+	{"First", func(s *viewer.State, c *calls.Call) string { return formatCallTime(c.First) },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.First.Before(b.First) }},
+	{"Last", func(s *viewer.State, c *calls.Call) string { return formatCallTime(c.Last) },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.Last.Before(b.Last) }},
+	{"Duration", func(s *viewer.State, c *calls.Call) string { return c.Duration().String() },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.Duration() < b.Duration() }},
+	{"Entries", func(s *viewer.State, c *calls.Call) string { return fmt.Sprintf("%d", len(c.Entries)) },
+		func(s *viewer.State, a, b *calls.Call) bool { return len(a.Entries) < len(b.Entries) }},
+	{"Level", func(s *viewer.State, c *calls.Call) string { return c.HighestLevel },
+		func(s *viewer.State, a, b *calls.Call) bool {
+			return parser.Severity(a.HighestLevel) < parser.Severity(b.HighestLevel)
+		}},
+	{"From", func(s *viewer.State, c *calls.Call) string { return c.From },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.From < b.From }},
+	{"To", func(s *viewer.State, c *calls.Call) string { return c.To },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.To < b.To }},
+	{"Final", func(s *viewer.State, c *calls.Call) string { return formatFinal(c) },
+		func(s *viewer.State, a, b *calls.Call) bool { return a.FinalCode < b.FinalCode }},
+	{"Legs", func(s *viewer.State, c *calls.Call) string { return fmt.Sprintf("%d", len(s.Calls.Legs(c.CallID))) },
+		func(s *viewer.State, a, b *calls.Call) bool {
+			return len(s.Calls.Legs(a.CallID)) < len(s.Calls.Legs(b.CallID))
+		}},
+	{"Flags", func(s *viewer.State, c *calls.Call) string { return strings.Join(c.Analysis(s.Store).Badges(), " ") },
+		func(s *viewer.State, a, b *calls.Call) bool {
+			return len(a.Analysis(s.Store).Anomalies) < len(b.Analysis(s.Store).Anomalies)
+		}},
+}
+
+func formatCallTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func formatFinal(c *calls.Call) string {
+	if c.FinalCode == 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%d %s", c.FinalCode, c.FinalReason))
+}
+
+// showCalls opens the list of all calls seen, Enter shows the entries of the selected call
+func showCalls() {
+	filterField := tview.NewInputField().
+		SetLabel("Filter: ").
+		SetPlaceholder("Call-ID, number or response")
+
+	table := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	table.SetBorder(true)
+
+	hint := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]Enter[-] Show call   [yellow]c[-] Show conversation   [yellow]L[-] Ladder   [yellow]p[-] PCAP   [yellow]s[-] Sort column   [yellow]r[-] Reverse   [yellow]/[-] Filter   [yellow]R[-] Refresh   [yellow]Esc[-] Close")
+
+	var shown []*calls.Call
+
+	refresh := func() {
+		query := strings.ToLower(strings.TrimSpace(filterField.GetText()))
+
+		view.Do(func(s *viewer.State) {
+			shown = shown[:0]
+			for _, call := range s.Calls.Calls() {
+				if query == "" || callMatches(s, call, query) {
+					shown = append(shown, call)
+				}
+			}
+
+			less := callColumns[callSortColumn].less
+			sort.SliceStable(shown, func(i, j int) bool {
+				if callSortDesc {
+					return less(s, shown[j], shown[i])
+				}
+				return less(s, shown[i], shown[j])
+			})
+
+			table.Clear()
+			for i, column := range callColumns {
+				title := column.title
+				if i == callSortColumn {
+					if callSortDesc {
+						title += " ▼"
+					} else {
+						title += " ▲"
+					}
+				}
+				table.SetCell(0, i, tview.NewTableCell(title).
+					SetTextColor(tcell.ColorYellow).
+					SetSelectable(false))
+			}
+
+			for row, call := range shown {
+				for i, column := range callColumns {
+					cell := tview.NewTableCell(tview.Escape(column.value(s, call)))
+					switch column.title {
+					case "Call-ID":
+						cell.SetTextColor(s.SessionColor(call.CallID)).SetExpansion(1)
+					case "Level":
+						cell.SetTextColor(parser.LevelColor(call.HighestLevel))
+					case "Final":
+						cell.SetTextColor(finalColor(call.FinalCode))
+					case "Flags":
+						cell.SetTextColor(tcell.ColorRed)
+					}
+					table.SetCell(row+1, i, cell)
+				}
+			}
+
+			table.SetTitle(fmt.Sprintf(" Calls: %d/%d ", len(shown), s.Calls.Len()))
+			if len(shown) > 0 {
+				row, _ := table.GetSelection()
+				if row < 1 || row > len(shown) {
+					table.Select(1, 0)
+				}
+			}
+		})
+	}
+
+	filterField.SetChangedFunc(func(text string) {
+		refresh()
+	})
+	filterField.SetDoneFunc(func(key tcell.Key) {
+		app.SetFocus(table)
+	})
+
+	table.SetSelectedFunc(func(row, column int) {
+		if row < 1 || row > len(shown) {
+			return
+		}
+		setCallFilter(shown[row-1].CallID)
+	})
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeDialog()
+			return nil
+		}
+
+		switch event.Rune() {
+		case 's':
+			callSortColumn = (callSortColumn + 1) % len(callColumns)
+			refresh()
+			return nil
+		case 'r':
+			callSortDesc = !callSortDesc
+			refresh()
+			return nil
+		case 'R':
+			refresh()
+			return nil
+		case 'L':
+			if row, _ := table.GetSelection(); row >= 1 && row <= len(shown) {
+				showLadder(shown[row-1].CallID)
+			}
+			return nil
+		case 'c':
+			if row, _ := table.GetSelection(); row >= 1 && row <= len(shown) {
+				var legs []string
+				view.Do(func(s *viewer.State) {
+					legs = s.Calls.Legs(shown[row-1].CallID)
+				})
+				setCallFilter(legs...)
+			}
+			return nil
+		case 'p':
+			if row, _ := table.GetSelection(); row >= 1 && row <= len(shown) {
+				call := shown[row-1]
+				showPcapPrompt(callEntries(call.CallID), "gofly-"+safeFileName(call.CallID)+".pcap")
+			}
+			return nil
+		case '/':
+			app.SetFocus(filterField)
+			return nil
+		}
+		return event
+	})
+
+	refresh()
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(filterField, 1, 0, false).
+		AddItem(table, 0, 1, true).
+		AddItem(hint, 1, 0, false)
+
+	showDialog(layout, table)
+}
+
+func callMatches(s *viewer.State, call *calls.Call, query string) bool {
+	badges := strings.Join(call.Analysis(s.Store).Badges(), " ")
+	for _, text := range []string{call.CallID, call.From, call.To, formatFinal(call), badges} {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func finalColor(code int) tcell.Color {
+	switch {
+	case code == 0:
+		return tcell.ColorGray
+	case code < 300:
+		return tcell.ColorGreen
+	case code < 400:
+		return tcell.ColorAqua
+	case code < 500:
+		return tcell.ColorYellow
+	default:
+		return tcell.ColorRed
+	}
+}
+
+// setCallFilter limits logTable to the entries of the given calls
+func setCallFilter(callIDs ...string) {
+	view.Do(func(s *viewer.State) {
+		s.Filters.Calls = make(map[string]bool)
+		for _, id := range callIDs {
+			s.Filters.Calls[id] = true
+		}
+	})
+
+	closeDialog()
+	applyFilter()
+	updateLevelStatus()
+}
+
+// showSelectedConversation limits logTable to all legs of the selected row's call
+func showSelectedConversation() {
+	row, _ := logTable.GetSelection()
+	if log, ok := rowEntry(row); ok && log.CallID != "" {
+		var legs []string
+		view.Do(func(s *viewer.State) {
+			legs = s.Calls.Legs(log.CallID)
+		})
+		setCallFilter(legs...)
+	}
+}
+
+// callEntries returns the positions of the entries of a call
+func callEntries(callID string) []int {
+	var positions []int
+	view.Do(func(s *viewer.State) {
+		if call := s.Calls.Get(callID); call != nil {
+			positions = slices.Clone(call.Entries)
+		}
+	})
+	return positions
+}
+
+func clearCallFilter() {
+	cleared := false
+	view.Do(func(s *viewer.State) {
+		if len(s.Filters.Calls) > 0 {
+			s.Filters.Calls = make(map[string]bool)
+			cleared = true
+		}
+	})
+	if cleared {
+		updateLevelStatus()
+	}
+}
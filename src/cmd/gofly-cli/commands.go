@@ -0,0 +1,474 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/config"
+	"gofly-cli/internal/export"
+	"gofly-cli/internal/parser"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variables overriding flags,
+// e.g. GOFLY_MAX_MEMORY for --max-memory
+const envPrefix = "GOFLY_"
+
+// globals are the flags shared by all commands
+type globals struct {
+	config  string
+	profile string
+	ip      string
+	port    string
+	levels  string
+	noIndex bool
+	links   []string
+}
+
+// options are the flags of a command, each command has its own
+type options struct {
+	*globals
+
+	// filters
+	level   string
+	from    string
+	to      string
+	last    string
+	calls   []string
+	exclude []string
+
+	after, before, context int
+
+	// interactive viewer
+	input      string
+	jump       string
+	maxEntries int
+	maxMemory  string
+	spill      bool
+	filters    string
+
+	// printed entries
+	regex    bool
+	invert   bool
+	format   string
+	color    string
+	output   string
+	maxCount int
+	count    bool
+	quiet    bool
+	timeout  time.Duration
+
+	json bool
+
+	// replay
+	speed float64
+	loop  bool
+	wait  bool
+}
+
+// matchCommands exit like grep: 0 when entries matched, 1 when none did,
+// 2 on errors
+var matchCommands = map[string]bool{"grep": true, "cat": true, "tail": true}
+
+// runCommand runs the command line and returns the exit code. Without a
+// subcommand the interactive viewer starts, as it did before subcommands.
+func runCommand(args []string) int {
+	code := 0
+	root := rootCommand(&globals{}, &code)
+
+	args = normalizeArgs(root, args)
+	root.SetArgs(args)
+	cmd, err := root.ExecuteC()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gofly-cli: %v\n", err)
+		if matchCommands[cmd.Name()] {
+			return 2
+		}
+		return 1
+	}
+	return code
+}
+
+// rootCommand builds the commands, those printing entries like grep set
+// code to their exit code
+func rootCommand(g *globals, code *int) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "gofly-cli",
+		Short: "CLI for gofly",
+		Long: "gofly-cli views, filters and replays gofly logs.\n\n" +
+			"Flags take their defaults from config.yaml or config.toml in the gofly-cli directory of the\n" +
+			"user config dir (~/.config/gofly-cli on Linux), or from --config. Its defaults apply to all\n" +
+			"commands, the profile selected with --profile or its profile key is applied over them.\n" +
+			"Environment variables " + envPrefix + "<FLAG> override the config file, e.g. " + envPrefix + "PROFILE=prod\n" +
+			"or " + envPrefix + "MAX_MEMORY=512MB.",
+		Version:       appVersion,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return applySettings(cmd, g)
+		},
+	}
+
+	f := root.PersistentFlags()
+	f.StringVar(&g.config, "config", "", "config file, YAML or TOML")
+	f.StringVar(&g.profile, "profile", "", "profile of the config file to use")
+	f.StringVar(&g.ip, "ip", "127.0.0.1", "server IP")
+	f.StringVar(&g.port, "port", "9090", "server port")
+	f.StringVar(&g.levels, "levels", "", "add custom log levels, NAME:SEVERITY[:COLOR],...")
+	f.BoolVar(&g.noIndex, "no-index", false, "don't read or write the sidecar index of files")
+	f.StringSliceVar(&g.links, "link-header", nil, "headers whose equal values link B2BUA legs into one conversation")
+	f.DurationVar(&calls.LinkWindow, "link-window", calls.LinkWindow, "link legs whose INVITEs have the same numbers within this time, 0 disables")
+	root.RegisterFlagCompletionFunc("profile", completeProfiles(g))
+
+	root.AddCommand(
+		viewCommand(g),
+		matchCommand(g, code, &cobra.Command{
+			Use:   "tail [flags] [PATTERN]",
+			Short: "Print the entries streamed by the server",
+			Long:  "Print the entries streamed by the server as they arrive, only those matching PATTERN if given.",
+			Args:  cobra.MaximumNArgs(1),
+		}),
+		matchCommand(g, code, &cobra.Command{
+			Use:   "grep [flags] PATTERN [FILE...]",
+			Short: "Print the entries matching PATTERN",
+			Long: "Print the entries of the files matching PATTERN, - reads stdin.\n" +
+				"Without files the entries streamed by the server are read until interrupted.",
+			Args: cobra.MinimumNArgs(1),
+		}),
+		matchCommand(g, code, &cobra.Command{
+			Use:   "cat [flags] [FILE...]",
+			Short: "Print the entries passing the filters",
+			Long: "Print the entries of the files passing the filters, - reads stdin.\n" +
+				"Without files the entries streamed by the server are read until interrupted.",
+		}),
+		statsCommand(g),
+		exportCommand(g),
+		serveCommand(g),
+	)
+	return root
+}
+
+func viewCommand(g *globals) *cobra.Command {
+	opts := &options{globals: g}
+	cmd := &cobra.Command{
+		Use:   "view [flags] [FILE | -]",
+		Short: "Browse a log file, stdin or the server interactively",
+		Long: "Browse the entries of a file, of stdin with -, or streamed by the server without FILE.\n" +
+			"This is the default command, press F1 for the hotkeys.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				opts.input = args[0]
+			}
+			return runView(opts)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&opts.input, "input", "I", "", "file to browse, - reads stdin")
+	f.MarkHidden("input")
+	f.StringVar(&opts.jump, "jump", "", "select the entry closest to this time after loading, e.g. 14:32")
+	addRetentionFlags(cmd, opts)
+	f.BoolVar(&opts.spill, "spill", false, "keep dropped entries in a temporary file, searchable with O")
+	f.StringVar(&opts.filters, "filters", "", "saved filters file, defaults to ./"+config.ProjectFiltersFile+" or the user config dir")
+	addFilterFlags(cmd, opts)
+	addContextFlags(cmd, opts)
+	return cmd
+}
+
+// matchCommand completes tail, grep or cat, which print entries like grep
+func matchCommand(g *globals, code *int, cmd *cobra.Command) *cobra.Command {
+	opts := &options{globals: g}
+	name := cmd.Name()
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		pattern, files := "", args
+		switch name {
+		case "tail":
+			files = nil
+			if len(args) > 0 {
+				pattern = args[0]
+			}
+		case "grep":
+			pattern, files = args[0], args[1:]
+		}
+
+		var err error
+		*code, err = grepEntries(opts, pattern, files)
+		return err
+	}
+
+	if name != "cat" {
+		cmd.Flags().BoolVarP(&opts.regex, "regex", "E", false, "PATTERN is a regular expression")
+		cmd.Flags().BoolVarP(&opts.invert, "invert-match", "v", false, "print the entries not matching PATTERN")
+	}
+	addFilterFlags(cmd, opts)
+	addContextFlags(cmd, opts)
+	addOutputFlags(cmd, opts, "text")
+	f := cmd.Flags()
+	f.IntVarP(&opts.maxCount, "max-count", "m", 0, "stop after N matches")
+	f.BoolVarP(&opts.count, "count", "c", false, "print only the number of matches")
+	f.BoolVarP(&opts.quiet, "quiet", "q", false, "print nothing, exit with 0 on the first match")
+	f.StringVar(&opts.color, "color", "auto", "color text output: auto, always or never")
+	cmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions(
+		[]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func statsCommand(g *globals) *cobra.Command {
+	opts := &options{globals: g}
+	cmd := &cobra.Command{
+		Use:   "stats [flags] [FILE...]",
+		Short: "Print call statistics",
+		Long: "Print the ASR, ACD, PDD and response codes of the calls in the files, - reads stdin.\n" +
+			"Without files the entries streamed by the server are read until interrupted.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printStats(opts, args)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&opts.from, "from", "", "count entries from this time, e.g. \"2006-01-02 15:04:05\" or 15:04")
+	f.StringVar(&opts.to, "to", "", "count entries up to this time")
+	f.StringVar(&opts.last, "last", "", "count entries of the last period before the newest entry, e.g. 5m")
+	f.BoolVar(&opts.json, "json", false, "print the statistics as JSON")
+	addRetentionFlags(cmd, opts)
+	f.StringVarP(&opts.output, "output", "o", "", "write to this file instead of stdout")
+	f.DurationVar(&opts.timeout, "timeout", 0, "stop reading the server after this time, e.g. 30s")
+	return cmd
+}
+
+func exportCommand(g *globals) *cobra.Command {
+	opts := &options{globals: g}
+	cmd := &cobra.Command{
+		Use:   "export [flags] [FILE...]",
+		Short: "Write the entries passing the filters to a file",
+		Long: "Write the entries of the files passing the filters as JSON lines, a JSON array, CSV, plain lines,\n" +
+			"HTML or PCAP, - reads stdin. The format defaults to the extension of --output, e.g. calls.html.\n" +
+			"Without files the entries streamed by the server are read until interrupted.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format, ok := export.FormatOf(opts.output); ok && !cmd.Flags().Changed("format") {
+				opts.format = format.String()
+			}
+			opts.color = "never"
+			_, err := grepEntries(opts, "", args)
+			return err
+		},
+	}
+
+	addFilterFlags(cmd, opts)
+	addOutputFlags(cmd, opts, "jsonl")
+	return cmd
+}
+
+func serveCommand(g *globals) *cobra.Command {
+	opts := &options{globals: g}
+	cmd := &cobra.Command{
+		Use:   "serve [flags] FILE...",
+		Short: "Replay log files to subscribers like a gofly server",
+		Long: "Listen on --ip and --port and stream the lines of the files to the clients subscribed with SUB,\n" +
+			"keeping the pace of their timestamps. It lets the viewer be tried out without a gofly server.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serveFiles(opts, args)
+		},
+	}
+
+	f := cmd.Flags()
+	f.Float64Var(&opts.speed, "speed", 1, "replay speed relative to the timestamps, 0 sends as fast as possible")
+	f.BoolVar(&opts.loop, "loop", false, "start over after the last file")
+	f.BoolVar(&opts.wait, "wait", true, "wait for the first subscriber before sending")
+	f.DurationVar(&opts.timeout, "timeout", 0, "stop serving after this time, e.g. 10m")
+	return cmd
+}
+
+func addFilterFlags(cmd *cobra.Command, opts *options) {
+	f := cmd.Flags()
+	f.StringVar(&opts.level, "level", "", "only entries of this level and above, e.g. WARN")
+	f.StringVar(&opts.from, "from", "", "only entries from this time, e.g. \"2006-01-02 15:04:05\" or 15:04")
+	f.StringVar(&opts.to, "to", "", "only entries up to this time")
+	f.StringVar(&opts.last, "last", "", "only entries of the last period before the newest entry, e.g. 5m")
+	f.StringArrayVar(&opts.calls, "call", nil, "only entries of this Call-ID, repeatable")
+	f.StringArrayVarP(&opts.exclude, "exclude", "x", nil, "hide entries matching this expression, repeatable")
+	cmd.RegisterFlagCompletionFunc("level", completeLevels)
+}
+
+func addContextFlags(cmd *cobra.Command, opts *options) {
+	f := cmd.Flags()
+	f.IntVarP(&opts.after, "after-context", "A", 0, "show N entries after each match")
+	f.IntVarP(&opts.before, "before-context", "B", 0, "show N entries before each match")
+	f.IntVarP(&opts.context, "context", "C", 0, "show N entries around each match")
+}
+
+func addRetentionFlags(cmd *cobra.Command, opts *options) {
+	f := cmd.Flags()
+	f.IntVar(&opts.maxEntries, "max-entries", 0, "keep at most N entries in memory, older ones are dropped")
+	f.StringVar(&opts.maxMemory, "max-memory", "", "memory budget for entries, older ones are dropped, e.g. 512MB")
+}
+
+func addOutputFlags(cmd *cobra.Command, opts *options, format string) {
+	f := cmd.Flags()
+	f.StringVar(&opts.format, "format", format, "output format: "+strings.Join(export.Names(), ", "))
+	f.StringVarP(&opts.output, "output", "o", "", "write to this file instead of stdout")
+	f.DurationVar(&opts.timeout, "timeout", 0, "stop reading the server after this time, e.g. 30s")
+	cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		export.Names(), cobra.ShellCompDirectiveNoFileComp))
+}
+
+// applySettings fills the flags not given on the command line from the
+// environment, then from the profile and the defaults of the config file,
+// and applies the global ones
+func applySettings(cmd *cobra.Command, g *globals) error {
+	flags := cmd.Flags()
+	for _, name := range []string{"config", "profile"} {
+		if err := setFromEnv(flags.Lookup(name)); err != nil {
+			return err
+		}
+	}
+
+	path, err := config.SettingsPath(g.config)
+	if err != nil {
+		return err
+	}
+	settings, err := config.LoadSettings(path)
+	if err != nil {
+		return err
+	}
+	values, err := settings.Values(g.profile)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed || f.Name == "help" || f.Name == "version" {
+			return
+		}
+		if err := setFromEnv(f); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if _, ok := os.LookupEnv(envName(f.Name)); ok {
+			return
+		}
+		if v, ok := values[f.Name]; ok {
+			if err := setFlag(f, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", path, f.Name, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	if err := parser.AddLevels(g.levels); err != nil {
+		return err
+	}
+	calls.LinkHeaders = append(calls.LinkHeaders, g.links...)
+	return nil
+}
+
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// setFromEnv sets f from its environment variable if there is one
+func setFromEnv(f *pflag.Flag) error {
+	if f == nil || f.Changed {
+		return nil
+	}
+	name := envName(f.Name)
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	if err := setFlag(f, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// setFlag sets a flag like the command line does, lists are separated by commas
+func setFlag(f *pflag.Flag, value string) error {
+	if list, ok := f.Value.(pflag.SliceValue); ok {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return list.Replace(items)
+	}
+	return f.Value.Set(value)
+}
+
+// normalizeArgs turns the single-dash long flags of old versions, like
+// -ip or -max-memory, into double-dash ones and starts the viewer when no
+// subcommand is given
+func normalizeArgs(root *cobra.Command, args []string) []string {
+	long := make(map[string]bool)
+	var collect func(cmd *cobra.Command)
+	collect = func(cmd *cobra.Command) {
+		for _, set := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags()} {
+			set.VisitAll(func(f *pflag.Flag) {
+				long[f.Name] = true
+			})
+		}
+		for _, sub := range cmd.Commands() {
+			collect(sub)
+		}
+	}
+	collect(root)
+
+	result := make([]string, 0, len(args)+1)
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && len(name) > 1 && long[name] {
+			arg = "-" + arg
+		}
+		result = append(result, arg)
+	}
+
+	if len(result) == 1 && (result[0] == "-h" || result[0] == "--help" || result[0] == "--version") {
+		return result
+	}
+	if len(result) > 0 {
+		switch result[0] {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return result
+		}
+	}
+	if cmd, _, err := root.Find(result); err == nil && cmd != root {
+		return result
+	}
+	return append([]string{"view"}, result...)
+}
+
+func completeProfiles(g *globals) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		path, err := config.SettingsPath(g.config)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		settings, err := config.LoadSettings(path)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return settings.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func completeLevels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := make([]string, len(parser.Levels))
+	for i, level := range parser.Levels {
+		names[i] = level.Name
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
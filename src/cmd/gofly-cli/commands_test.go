@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const testSettings = `profile: lab
+defaults:
+  ip: 10.0.0.1
+  port: 5000
+  max-memory: 1GB
+profiles:
+  lab:
+    ip: 10.0.0.2
+  prod:
+    ip: 10.0.0.3
+    max-memory: 2GB
+`
+
+// probeCommand adds a command to root that records the settings it runs with
+func probeCommand(root *cobra.Command, g *globals) *options {
+	opts := &options{globals: g}
+	cmd := &cobra.Command{
+		Use:  "probe",
+		RunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	addRetentionFlags(cmd, opts)
+	root.AddCommand(cmd)
+	return opts
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gofly-cli", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(testSettings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.yaml")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		env       map[string]string
+		ip, port  string
+		maxMemory string
+		err       bool
+	}{
+		{name: "built-in defaults", args: []string{"--config", empty}, ip: "127.0.0.1", port: "9090"},
+		{name: "profile of the file over its defaults", ip: "10.0.0.2", port: "5000", maxMemory: "1GB"},
+		{name: "selected profile", args: []string{"--profile", "prod"}, ip: "10.0.0.3", port: "5000", maxMemory: "2GB"},
+		{name: "profile from the environment", env: map[string]string{"GOFLY_PROFILE": "prod"},
+			ip: "10.0.0.3", port: "5000", maxMemory: "2GB"},
+		{name: "profile flag over the environment", args: []string{"--profile", "lab"}, env: map[string]string{"GOFLY_PROFILE": "prod"},
+			ip: "10.0.0.2", port: "5000", maxMemory: "1GB"},
+		{name: "environment over the profile", args: []string{"--profile", "prod"},
+			env: map[string]string{"GOFLY_IP": "10.0.0.4", "GOFLY_MAX_MEMORY": "3GB"}, ip: "10.0.0.4", port: "5000", maxMemory: "3GB"},
+		{name: "environment over built-in defaults", args: []string{"--config", empty}, env: map[string]string{"GOFLY_PORT": "7000"},
+			ip: "127.0.0.1", port: "7000"},
+		{name: "flags over the environment", args: []string{"--ip", "10.0.0.5", "--max-memory", "4GB"},
+			env: map[string]string{"GOFLY_IP": "10.0.0.4", "GOFLY_MAX_MEMORY": "3GB"}, ip: "10.0.0.5", port: "5000", maxMemory: "4GB"},
+		{name: "single-dash flags", args: []string{"-ip", "10.0.0.6", "-port=6000"}, ip: "10.0.0.6", port: "6000", maxMemory: "1GB"},
+		{name: "unknown profile", args: []string{"--profile", "test"}, err: true},
+		{name: "invalid environment value", env: map[string]string{"GOFLY_MAX_ENTRIES": "many"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", dir)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			g := &globals{}
+			code := 0
+			root := rootCommand(g, &code)
+			opts := probeCommand(root, g)
+			root.SetArgs(normalizeArgs(root, append([]string{"probe"}, tt.args...)))
+			root.SetErr(new(discard))
+			_, err := root.ExecuteC()
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if g.ip != tt.ip || g.port != tt.port || opts.maxMemory != tt.maxMemory {
+				t.Errorf("ip %q, port %q, max-memory %q, want %q, %q, %q",
+					g.ip, g.port, opts.maxMemory, tt.ip, tt.port, tt.maxMemory)
+			}
+		})
+	}
+}
+
+// discard swallows the usage printed on errors
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestNormalizeArgs(t *testing.T) {
+	code := 0
+	root := rootCommand(&globals{}, &code)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-ip", "10.0.0.1", "-port", "9091"}, []string{"view", "--ip", "10.0.0.1", "--port", "9091"}},
+		{[]string{"-I", "a.log"}, []string{"view", "-I", "a.log"}},
+		{[]string{"-ip=10.0.0.1", "-max-memory", "1GB", "a.log"}, []string{"view", "--ip=10.0.0.1", "--max-memory", "1GB", "a.log"}},
+		{[]string{"a.log"}, []string{"view", "a.log"}},
+		{nil, []string{"view"}},
+		{[]string{"grep", "-ip", "h", "-Ev", "a|b", "-m", "1"}, []string{"grep", "--ip", "h", "-Ev", "a|b", "-m", "1"}},
+		{[]string{"grep", "x", "--", "-ip"}, []string{"grep", "x", "--", "-ip"}},
+		{[]string{"--port", "9091", "stats"}, []string{"--port", "9091", "stats"}},
+		{[]string{"-h"}, []string{"-h"}},
+		{[]string{"--version"}, []string{"--version"}},
+		{[]string{"help", "grep"}, []string{"help", "grep"}},
+		{[]string{"completion", "bash"}, []string{"completion", "bash"}},
+	}
+	for _, tt := range tests {
+		if got := normalizeArgs(root, tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("normalizeArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
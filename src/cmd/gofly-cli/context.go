@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/viewer"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	contextTextColor = tcell.NewHexColor(0x808080)
+	contextBgColor   = tcell.NewHexColor(0x181818)
+)
+
+// contextCells renders a context entry dimmed
+func contextCells(log model.LogEntry) []*tview.TableCell {
+	cells := []*tview.TableCell{
+		tview.NewTableCell(log.Index),
+		tview.NewTableCell(tview.Escape(log.Timestamp)),
+		tview.NewTableCell(tview.Escape(log.Level)).SetAlign(tview.AlignCenter),
+		tview.NewTableCell(tview.Escape(log.Message)),
+	}
+
+	for _, cell := range cells {
+		cell.SetTextColor(contextTextColor).
+			SetBackgroundColor(contextBgColor)
+	}
+
+	return cells
+}
+
+func separatorCells() []*tview.TableCell {
+	cells := make([]*tview.TableCell, 4)
+	for i := range cells {
+		text := ""
+		if i == 0 {
+			text = "--"
+		}
+		cells[i] = tview.NewTableCell(text).
+			SetTextColor(contextTextColor).
+			SetSelectable(false)
+	}
+	return cells
+}
+
+// setContext parses "N" (before and after) or "BEFORE,AFTER"
+func setContext(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = "0"
+	}
+
+	beforeText, afterText, found := strings.Cut(text, ",")
+	if !found {
+		afterText = beforeText
+	}
+
+	before, err := strconv.Atoi(strings.TrimSpace(beforeText))
+	if err != nil || before < 0 {
+		return fmt.Errorf("invalid context %q", beforeText)
+	}
+	after, err := strconv.Atoi(strings.TrimSpace(afterText))
+	if err != nil || after < 0 {
+		return fmt.Errorf("invalid context %q", afterText)
+	}
+
+	view.Do(func(s *viewer.State) {
+		s.Before, s.After = before, after
+	})
+	rebuildTable()
+	updateLevelStatus()
+
+	return nil
+}
+
+func showContextPrompt() {
+	var initial string
+	view.Do(func(s *viewer.State) {
+		initial = fmt.Sprintf("%d,%d", s.Before, s.After)
+	})
+	showPrompt(" Context Lines (N or BEFORE,AFTER) ", "Context: ", initial, setContext)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/sip"
+	"gofly-cli/internal/viewer"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	// content holds logTable and the detail pane when it is visible
+	content    *tview.Flex
+	detailView *tview.TextView
+
+	detailVisible bool
+	detailSide    bool
+)
+
+func initDetailPane() {
+	detailView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true).
+		SetScrollable(true)
+	detailView.SetBorder(true).SetTitle(" Details ")
+
+	content = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(logTable, 0, 1, true)
+
+	logTable.SetSelectionChangedFunc(func(row, column int) {
+		if detailVisible {
+			updateDetail(row)
+		}
+	})
+	logTable.SetSelectedFunc(func(row, column int) {
+		toggleDetail()
+	})
+}
+
+func toggleDetail() {
+	detailVisible = !detailVisible
+	layoutContent()
+}
+
+// toggleDetailSide moves the detail pane between the bottom and the right side
+func toggleDetailSide() {
+	detailSide = !detailSide
+	layoutContent()
+}
+
+func layoutContent() {
+	content.Clear()
+
+	if detailSide {
+		content.SetDirection(tview.FlexColumn)
+	} else {
+		content.SetDirection(tview.FlexRow)
+	}
+
+	content.AddItem(logTable, 0, 1, true)
+	if detailVisible {
+		content.AddItem(detailView, 0, 1, false)
+		row, _ := logTable.GetSelection()
+		updateDetail(row)
+	} else if app.GetFocus() == detailView {
+		app.SetFocus(logTable)
+	}
+}
+
+func updateDetail(row int) {
+	var log model.LogEntry
+	var lines []string
+	ok := false
+	view.Do(func(s *viewer.State) {
+		if log, ok = s.Entry(row - 1); ok {
+			lines = messageLines(s.Store, s.Rows[row-1].Pos, log)
+		}
+	})
+	if !ok {
+		detailView.SetText("")
+		return
+	}
+
+	detailView.SetText(formatDetail(log, lines))
+	detailView.ScrollToBeginning()
+}
+
+// maxMessageLines bounds the entries joined into the SIP message of an entry
+const maxMessageLines = 200
+
+// messageLines returns the message of log at pos followed by the lines of
+// the entries after it without a timestamp of their own, where a SIP message
+// logged one line per entry continues
+func messageLines(logs model.Entries, pos int, log model.LogEntry) []string {
+	lines := []string{log.Message}
+	for next := pos + 1; len(lines) < maxMessageLines; next++ {
+		e, ok := logs.Get(next)
+		if !ok || e.Timestamp != "" {
+			break
+		}
+		lines = append(lines, e.OriginalMessage)
+	}
+	return lines
+}
+
+// formatDetail describes log, lines are the texts its SIP message is parsed
+// from, only the message of log when nil
+func formatDetail(log model.LogEntry, lines []string) string {
+	var text strings.Builder
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&text, "[yellow]%-10s[-] %s\n", name+":", tview.Escape(value))
+		}
+	}
+
+	field("Index", log.Index)
+	field("Timestamp", log.Timestamp)
+	if !log.Time.IsZero() {
+		field("Parsed", log.Time.Format("2006-01-02 15:04:05.000 MST (-07:00)"))
+	}
+	fmt.Fprintf(&text, "[yellow]%-10s[-] [%s]%s[-]\n", "Level:", colorTag(log.LevelColor), tview.Escape(log.Level))
+	field("Call-ID", log.CallID)
+	var legs []string
+	var analysis *calls.Analysis
+	view.Do(func(s *viewer.State) {
+		legs = s.Calls.Legs(log.CallID)
+		if call := s.Calls.Get(log.CallID); call != nil {
+			analysis = call.Analysis(s.Store)
+		}
+	})
+	if log.CallID != "" && len(legs) > 1 {
+		field("Legs", strings.Join(legs, ", "))
+	}
+	field("Source", log.Source)
+
+	if analysis != nil {
+		text.WriteString(formatAnalysis(analysis))
+	}
+
+	if lines == nil {
+		lines = []string{log.Message}
+	}
+	if msg, ok := sip.ParseLines(lines); ok {
+		text.WriteString("\n[green]SIP message[-]\n")
+		text.WriteString(formatSIP(msg))
+	}
+
+	text.WriteString("\n[yellow]Message:[-]\n")
+	text.WriteString(tview.Escape(log.Message))
+	text.WriteString("\n\n[yellow]Raw line:[-]\n")
+	text.WriteString(tview.Escape(log.RawLine()))
+	text.WriteString("\n")
+
+	return text.String()
+}
+
+func formatSIP(msg *sip.Message) string {
+	var text strings.Builder
+
+	fmt.Fprintf(&text, "[::b]%s[::-]\n", tview.Escape(msg.StartLine()))
+	for _, h := range msg.Headers {
+		fmt.Fprintf(&text, "[aqua]%s[-]: %s\n", tview.Escape(h.Name), tview.Escape(h.Value))
+	}
+	if msg.Body != "" {
+		text.WriteString("\n")
+		text.WriteString(tview.Escape(msg.Body))
+		text.WriteString("\n")
+	}
+
+	return text.String()
+}
+
+func formatAnalysis(a *calls.Analysis) string {
+	var text strings.Builder
+
+	text.WriteString("\n[green]Call analysis[-]\n")
+	if a.PostDialDelay > 0 {
+		fmt.Fprintf(&text, "[yellow]%-10s[-] %s\n", "PDD:", a.PostDialDelay)
+	}
+	if a.Answered {
+		fmt.Fprintf(&text, "[yellow]%-10s[-] %s\n", "Talk time:", a.TalkTime())
+	}
+
+	if len(a.Anomalies) == 0 {
+		text.WriteString("[green]no anomalies[-]\n")
+		return text.String()
+	}
+	for _, anomaly := range a.Anomalies {
+		fmt.Fprintf(&text, "[black:red] %s [-:-] %s\n", anomaly.Flag.Badge(), tview.Escape(anomaly.Detail))
+	}
+
+	return text.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/viewer"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showExclusions opens the exclusions dialog: patterns whose matching rows are hidden
+func showExclusions() {
+	list := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	list.SetBorder(true).SetTitle(" Exclusions ")
+
+	hint := tview.NewTextView().SetDynamicColors(true)
+
+	addInput := tview.NewInputField().
+		SetLabel("Exclude: ").
+		SetPlaceholder("text, re:<pattern> or /<pattern>/")
+
+	refreshList := func() {
+		list.Clear()
+		for i, h := range []string{"On", "Pattern", "Mode", "Suppressed"} {
+			list.SetCell(0, i, tview.NewTableCell(h).
+				SetTextColor(tcell.ColorYellow).
+				SetSelectable(false))
+		}
+
+		view.Do(func(s *viewer.State) {
+			exclusions := &s.Filters.Exclusions
+			for i, excl := range exclusions.Items {
+				on := "[ ]"
+				if excl.Enabled {
+					on = "[x]"
+				}
+				list.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(on)))
+				list.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(excl.Matcher.Expr)).SetExpansion(1))
+				list.SetCell(i+1, 2, tview.NewTableCell(excl.Matcher.Mode.String()))
+				list.SetCell(i+1, 3, tview.NewTableCell(fmt.Sprintf("%d", excl.Suppressed)).SetAlign(tview.AlignRight))
+			}
+
+			hint.SetText(fmt.Sprintf(
+				"Hidden rows: [red]%d[-]    "+
+					"[yellow]Space[-] Toggle   [yellow]d[-] Delete   [yellow]Tab[-] Add   [yellow]Esc[-] Close",
+				exclusions.Hidden))
+		})
+	}
+
+	edit := func(fn func(exclusions *filter.ExclusionList)) {
+		view.Do(func(s *viewer.State) {
+			fn(&s.Filters.Exclusions)
+		})
+		rebuildTable()
+		refreshList()
+	}
+
+	addInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			text := strings.TrimSpace(addInput.GetText())
+			if text == "" {
+				return
+			}
+
+			matcher, err := filter.Compile(text, filterMode)
+			if err != nil {
+				hint.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+				return
+			}
+
+			edit(func(exclusions *filter.ExclusionList) {
+				exclusions.Add(matcher)
+			})
+			addInput.SetText("")
+			list.Select(exclusionCount(), 0)
+		case tcell.KeyEsc, tcell.KeyTab:
+			app.SetFocus(list)
+		}
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := list.GetSelection()
+		idx := row - 1
+
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closeDialog()
+			return nil
+		case tcell.KeyTab:
+			app.SetFocus(addInput)
+			return nil
+		case tcell.KeyDelete:
+			edit(func(exclusions *filter.ExclusionList) {
+				exclusions.Remove(idx)
+			})
+			return nil
+		}
+
+		switch event.Rune() {
+		case ' ':
+			if idx >= 0 && idx < exclusionCount() {
+				edit(func(exclusions *filter.ExclusionList) {
+					exclusions.Items[idx].Enabled = !exclusions.Items[idx].Enabled
+				})
+			}
+			return nil
+		case 'd':
+			edit(func(exclusions *filter.ExclusionList) {
+				exclusions.Remove(idx)
+			})
+			return nil
+		}
+
+		return event
+	})
+
+	refreshList()
+	if exclusionCount() > 0 {
+		list.Select(1, 0)
+	}
+
+	dialog := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(list, 0, 1, false).
+		AddItem(hint, 1, 0, false).
+		AddItem(addInput, 1, 0, true)
+
+	// keep the dialog small and centered over the screen
+	layout := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(dialog, 20, 0, true).
+			AddItem(nil, 0, 1, false), 100, 0, true).
+		AddItem(nil, 0, 1, false)
+
+	showDialog(layout, addInput)
+}
+
+func exclusionCount() int {
+	count := 0
+	view.Do(func(s *viewer.State) {
+		count = len(s.Filters.Exclusions.Items)
+	})
+	return count
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/export"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/viewer"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showExport asks which rows to export: the call of the selected row, the
+// rows currently displayed in logTable or all entries. The format follows
+// the extension of the file name.
+func showExport() {
+	row, _ := logTable.GetSelection()
+	log, hasEntry := rowEntry(row)
+
+	buttons := []string{"Displayed rows", "All", "Cancel"}
+	if hasEntry && log.CallID != "" {
+		buttons = append([]string{"Selected call"}, buttons...)
+	}
+
+	modal := tview.NewModal().
+		SetText("Export rows to JSON, JSONL, CSV, HTML or plain lines").
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			switch buttonLabel {
+			case "Selected call":
+				showExportPrompt(positionRows(callEntries(log.CallID)), "gofly-"+safeFileName(log.CallID)+".html")
+			case "Displayed rows":
+				var rows []viewer.Row
+				view.Do(func(s *viewer.State) {
+					rows = append(rows, s.Rows...)
+				})
+				showExportPrompt(rows, "gofly-filtered.html")
+			case "All":
+				var positions []int
+				view.Do(func(s *viewer.State) {
+					for pos := s.Store.First(); pos < s.Store.Len(); pos++ {
+						positions = append(positions, pos)
+					}
+				})
+				showExportPrompt(positionRows(positions), "gofly.html")
+			default:
+				closeDialog()
+			}
+		})
+	showDialog(modal, modal)
+}
+
+func showExportPrompt(rows []viewer.Row, initial string) {
+	showPrompt(" Export (.json .jsonl .csv .html .log) ", "File: ", initial, func(path string) error {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return fmt.Errorf("empty file name")
+		}
+		format, ok := export.FormatOf(path)
+		if !ok {
+			return fmt.Errorf("unknown extension, use .json, .jsonl, .csv, .html or .log")
+		}
+
+		n, err := writeExport(path, format, rows)
+		if err != nil {
+			return err
+		}
+		showMessage(fmt.Sprintf("Wrote %d rows to %s", n, path))
+		return nil
+	})
+}
+
+// exportBatch is the number of rows copied from the viewer at a time, the
+// file is written without holding it
+const exportBatch = 4096
+
+// writeExport writes the entries of rows still in the store to path and
+// returns their number
+func writeExport(path string, format export.Format, rows []viewer.Row) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var highlight *filter.Matcher
+	view.Do(func(s *viewer.State) {
+		highlight = s.Filters.Matcher
+	})
+
+	// colors of the entries of the current batch, by their number in the export
+	var colors [][2]tcell.Color
+	first := 0
+	out := export.NewWriter(file, format, export.Options{
+		Highlight: highlight,
+		Title:     "gofly-cli " + currentMode,
+		RowColors: func(e model.LogEntry, n int) (tcell.Color, tcell.Color) {
+			c := colors[n-first]
+			return c[0], c[1]
+		},
+	})
+
+	n := 0
+	var batch []viewer.Row
+	var entries []model.LogEntry
+	for start := 0; start < len(rows) && err == nil; start += exportBatch {
+		// entries evicted meanwhile are left out
+		batch, entries, colors = batch[:0], entries[:0], colors[:0]
+		first = n
+		view.Do(func(s *viewer.State) {
+			for _, r := range rows[start:min(start+exportBatch, len(rows))] {
+				if r.Kind == viewer.SeparatorRow {
+					batch = append(batch, r)
+					continue
+				}
+				log, ok := s.Store.Get(r.Pos)
+				if !ok {
+					continue
+				}
+				bg, fg := rowColors(s, first+len(entries), log.CallID)
+				batch = append(batch, r)
+				entries = append(entries, log)
+				colors = append(colors, [2]tcell.Color{bg, fg})
+			}
+		})
+
+		i := 0
+		for _, r := range batch {
+			if err != nil {
+				break
+			}
+			if r.Kind == viewer.SeparatorRow {
+				err = out.Separator()
+				continue
+			}
+			err = out.Write(entries[i], r.Kind == viewer.ContextRow)
+			i++
+			n++
+		}
+	}
+
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// positionRows shows the entries at positions as matches
+func positionRows(positions []int) []viewer.Row {
+	rows := make([]viewer.Row, len(positions))
+	for i, pos := range positions {
+		rows[i] = viewer.Row{Pos: pos, Kind: viewer.MatchRow}
+	}
+	return rows
+}
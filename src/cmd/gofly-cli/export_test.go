@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"gofly-cli/internal/export"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	files := grepFiles(t, grepLines)
+	dir := t.TempDir()
+
+	// the format follows the extension unless --format is given
+	array := filepath.Join(dir, "rows.json")
+	lines := filepath.Join(dir, "rows.json.txt")
+	if code := runCommand([]string{"export", "--no-index", "--level", "error", "-o", array, files[0]}); code != 0 {
+		t.Fatalf("export to %s: exit code %d", array, code)
+	}
+	if code := runCommand([]string{"export", "--no-index", "--format", "jsonl", "-o", lines, files[0]}); code != 0 {
+		t.Fatalf("export to %s: exit code %d", lines, code)
+	}
+
+	data, err := os.ReadFile(array)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []export.Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("%s is no JSON array: %v", array, err)
+	}
+	if len(records) != 1 || records[0].Line != grepLines[7] {
+		t.Errorf("records %+v, want line %q", records, grepLines[7])
+	}
+
+	data, err = os.ReadFile(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != len(grepLines) || !strings.HasPrefix(string(data), `{"index"`) {
+		t.Errorf("%s holds %d lines:\n%s", lines, n, data)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/viewer"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var exprColors = []struct {
+	name  string
+	color tcell.Color
+}{
+	{"orange", tcell.ColorOrange},
+	{"aqua", tcell.ColorAqua},
+	{"lime", tcell.ColorLime},
+	{"fuchsia", tcell.ColorFuchsia},
+	{"tomato", tcell.ColorTomato},
+	{"violet", tcell.ColorViolet},
+	{"gold", tcell.ColorGold},
+}
+
+// showExpressions opens the match expressions dialog:
+// a list of expressions on top and a form to add new ones below
+func showExpressions() {
+	list := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	list.SetBorder(true).SetTitle(" Match Expressions ")
+
+	hint := tview.NewTextView().SetDynamicColors(true)
+
+	form := tview.NewForm()
+	form.SetBorder(true).SetTitle(" Add Expression ")
+
+	refreshList := func() {
+		list.Clear()
+		for i, h := range []string{"On", "Name", "Expression", "Mode", "Hits"} {
+			list.SetCell(0, i, tview.NewTableCell(h).
+				SetTextColor(tcell.ColorYellow).
+				SetSelectable(false))
+		}
+
+		view.Do(func(s *viewer.State) {
+			exprs := &s.Filters.Expressions
+			for i, expr := range exprs.Items {
+				on := "[ ]"
+				if expr.Enabled {
+					on = "[x]"
+				}
+				list.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(on)))
+				list.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(expr.Name)).SetTextColor(expr.Color))
+				list.SetCell(i+1, 2, tview.NewTableCell(tview.Escape(expr.Matcher.Expr)).SetExpansion(1))
+				list.SetCell(i+1, 3, tview.NewTableCell(expr.Matcher.Mode.String()))
+				list.SetCell(i+1, 4, tview.NewTableCell(fmt.Sprintf("%d", expr.Hits)).SetAlign(tview.AlignRight))
+			}
+
+			hint.SetText(fmt.Sprintf(
+				"Show rows matching: [green]%s[-]    "+
+					"[yellow]Space[-] Toggle   [yellow]d[-] Delete   [yellow]a[-] Add   [yellow]m[-] Mode   [yellow]Esc[-] Close",
+				exprs.Mode))
+		})
+	}
+
+	// hits are recounted and the table rebuilt on every change
+	edit := func(fn func(exprs *filter.ExpressionList)) {
+		view.Do(func(s *viewer.State) {
+			fn(&s.Filters.Expressions)
+			s.RecountExpressions()
+		})
+		rebuildTable()
+		refreshList()
+	}
+
+	colorNames := make([]string, len(exprColors))
+	for i, c := range exprColors {
+		colorNames[i] = c.name
+	}
+
+	form.
+		AddInputField("Name", "", 30, nil, nil).
+		AddInputField("Expression", "", 50, nil, nil).
+		AddCheckbox("Regex", filterMode == filter.ModeRegex, nil).
+		AddDropDown("Color", colorNames, expressionCount()%len(exprColors), nil).
+		AddButton("Add", func() {
+			name := form.GetFormItemByLabel("Name").(*tview.InputField)
+			text := form.GetFormItemByLabel("Expression").(*tview.InputField)
+			regex := form.GetFormItemByLabel("Regex").(*tview.Checkbox)
+			color := form.GetFormItemByLabel("Color").(*tview.DropDown)
+
+			mode := filter.ModeText
+			if regex.IsChecked() {
+				mode = filter.ModeRegex
+			}
+
+			matcher, err := filter.Compile(strings.TrimSpace(text.GetText()), mode)
+			if err != nil {
+				hint.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+				return
+			}
+
+			colorIdx, _ := color.GetCurrentOption()
+			edit(func(exprs *filter.ExpressionList) {
+				exprs.Add(strings.TrimSpace(name.GetText()), matcher, exprColors[colorIdx].color)
+			})
+
+			name.SetText("")
+			text.SetText("")
+			count := expressionCount()
+			color.SetCurrentOption(count % len(exprColors))
+
+			list.Select(count, 0)
+			app.SetFocus(list)
+		}).
+		AddButton("Close", closeDialog)
+
+	form.SetCancelFunc(func() {
+		app.SetFocus(list)
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := list.GetSelection()
+		idx := row - 1
+
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closeDialog()
+			return nil
+		case tcell.KeyEnter:
+			if idx >= 0 && idx < expressionCount() {
+				edit(func(exprs *filter.ExpressionList) {
+					exprs.Items[idx].Enabled = !exprs.Items[idx].Enabled
+				})
+			}
+			return nil
+		case tcell.KeyDelete:
+			edit(func(exprs *filter.ExpressionList) {
+				exprs.Remove(idx)
+			})
+			return nil
+		}
+
+		switch event.Rune() {
+		case ' ':
+			if idx >= 0 && idx < expressionCount() {
+				edit(func(exprs *filter.ExpressionList) {
+					exprs.Items[idx].Enabled = !exprs.Items[idx].Enabled
+				})
+			}
+			return nil
+		case 'd':
+			edit(func(exprs *filter.ExpressionList) {
+				exprs.Remove(idx)
+			})
+			return nil
+		case 'a':
+			app.SetFocus(form)
+			return nil
+		case 'm':
+			edit(func(exprs *filter.ExpressionList) {
+				exprs.Mode = exprs.Mode.Next()
+			})
+			return nil
+		}
+
+		return event
+	})
+
+	refreshList()
+	if expressionCount() > 0 {
+		list.Select(1, 0)
+	}
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(list, 0, 1, true).
+		AddItem(hint, 1, 0, false).
+		AddItem(form, 13, 0, false)
+
+	if expressionCount() == 0 {
+		showDialog(layout, form)
+	} else {
+		showDialog(layout, list)
+	}
+}
+
+func expressionCount() int {
+	count := 0
+	view.Do(func(s *viewer.State) {
+		count = len(s.Filters.Expressions.Items)
+	})
+	return count
+}
+
+// updateExprStatus shows hit counts of match expressions and
+// suppressed counts of exclusions in the third status line
+func updateExprStatus() {
+	var text strings.Builder
+
+	view.Do(func(s *viewer.State) {
+		exprs, exclusions := &s.Filters.Expressions, &s.Filters.Exclusions
+		if len(exprs.Items) == 0 {
+			text.WriteString("Match Expressions: 0")
+		} else {
+			fmt.Fprintf(&text, "Match Expressions (%s):", exprs.Mode)
+		}
+
+		for _, expr := range exprs.Items {
+			color := colorTag(expr.Color)
+			if !expr.Enabled {
+				color = "gray"
+			}
+			fmt.Fprintf(&text, "   [%s]%s[-] %d", color, tview.Escape(expr.Name), expr.Hits)
+		}
+
+		if len(exclusions.Items) > 0 {
+			fmt.Fprintf(&text, "    Excluded: %d", exclusions.Hidden)
+			for _, excl := range exclusions.Items {
+				if !excl.Enabled {
+					continue
+				}
+				fmt.Fprintf(&text, "   [red]-%s[-] %d", tview.Escape(excl.Matcher.Expr), excl.Suppressed)
+			}
+		}
+	})
+
+	exprText.SetText(text.String())
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"gofly-cli/internal/export"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/loader"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/source"
+	"gofly-cli/internal/viewer"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/term"
+)
+
+const (
+	// entries kept per input for context rows, a batch never exceeds it
+	grepWindow = 1 << 16
+	grepBatch  = 4096
+	// the newest entry of a file is searched in its last bytes
+	tailSize = 64 << 10
+)
+
+// grepInput is one file, stdin or the server
+type grepInput struct {
+	src  source.Source
+	name string
+	// relative time ranges count back from it
+	ref time.Time
+}
+
+// grepEntries prints the entries of the files, or of the server without
+// files, that pass the filters and match pattern. It returns the exit code.
+func grepEntries(opts *options, pattern string, files []string) (int, error) {
+	// an existing index spares parsing, a read-only command doesn't write one
+	loader.UseIndex = !opts.noIndex
+	loader.BuildIndex = false
+	if opts.context > 0 {
+		opts.before, opts.after = opts.context, opts.context
+	}
+
+	format, err := export.ParseFormat(opts.format)
+	if err != nil {
+		return 2, err
+	}
+
+	mode := filter.ModeText
+	if opts.regex {
+		mode = filter.ModeRegex
+	}
+	var matcher *filter.Matcher
+	if pattern != "" {
+		if matcher, err = filter.Compile(pattern, mode); err != nil {
+			return 2, err
+		}
+	}
+	var exclusions []*filter.Matcher
+	for _, expr := range opts.exclude {
+		m, err := filter.Compile(expr, mode)
+		if err != nil {
+			return 2, fmt.Errorf("--exclude %s: %w", expr, err)
+		}
+		exclusions = append(exclusions, m)
+	}
+
+	minSeverity, err := severityOf(opts.level)
+	if err != nil {
+		return 2, err
+	}
+
+	inputs, err := grepInputs(opts, files)
+	if err != nil {
+		return 2, err
+	}
+
+	w, closeOutput, err := createOutput(opts.output)
+	if err != nil {
+		return 2, err
+	}
+	defer closeOutput()
+	color, err := useColor(opts.color, w)
+	if err != nil {
+		return 2, err
+	}
+	highlight := matcher
+	if opts.invert {
+		highlight = nil
+	}
+	// rows are written while the viewer of the input holds its state
+	var state *viewer.State
+	out := export.NewWriter(w, format, export.Options{
+		Color:     color,
+		Highlight: highlight,
+		Title:     "gofly-cli " + strings.Join(files, " "),
+		RowColors: func(e model.LogEntry, n int) (tcell.Color, tcell.Color) {
+			return rowColors(state, n, e.CallID)
+		},
+	})
+
+	ctx, cancel := runContext(opts.timeout)
+	defer cancel()
+
+	total := 0
+	var errs []error
+	for _, in := range inputs {
+		timeRange, err := filter.ParseTimeRange(timeRangeSpec(opts.from, opts.to, opts.last), in.ref)
+		if err != nil {
+			return 2, err
+		}
+
+		view, err := viewer.New(viewer.Options{MaxEntries: grepWindow, Stream: true})
+		if err != nil {
+			return 2, err
+		}
+		view.Do(func(s *viewer.State) {
+			state = s
+			if matcher != nil && opts.invert {
+				s.Filters.Exclusions.Add(matcher)
+			} else if matcher != nil {
+				s.Filters.Text = matcher.Expr
+				s.Filters.Matcher = matcher
+			}
+			for _, m := range exclusions {
+				s.Filters.Exclusions.Add(m)
+			}
+			for _, id := range opts.calls {
+				s.Filters.Calls[id] = true
+			}
+			s.Filters.MinSeverity = minSeverity
+			s.Filters.TimeRange = timeRange
+			s.Before, s.After = opts.before, opts.after
+		})
+
+		limit := 0
+		if opts.maxCount > 0 {
+			limit = opts.maxCount
+		} else if opts.quiet {
+			limit = 1
+		}
+		matched, err := grepSource(ctx, in.src, view, out, limit, opts.count || opts.quiet)
+		view.Close()
+		total += matched
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if opts.count {
+			if len(inputs) > 1 {
+				fmt.Fprintf(w, "%s:%d\n", in.name, matched)
+			} else {
+				fmt.Fprintf(w, "%d\n", matched)
+			}
+		}
+		if (opts.quiet && total > 0) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := closeOutput(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil && !(opts.quiet && total > 0) {
+		return 2, err
+	}
+	if total == 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// grepInputs returns the inputs of the files, - is stdin, or the server
+// when there are none
+func grepInputs(opts *options, files []string) ([]grepInput, error) {
+	var inputs []grepInput
+	for _, path := range files {
+		if path == "-" {
+			inputs = append(inputs, grepInput{src: source.Stdin(), name: "(standard input)", ref: time.Now()})
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, grepInput{src: source.NewFile(path), name: path, ref: newestTime(path)})
+	}
+	if len(inputs) == 0 {
+		addr := fmt.Sprintf("%s:%s", opts.ip, opts.port)
+		inputs = append(inputs, grepInput{src: source.NewUDP(addr), name: addr, ref: time.Now()})
+	}
+	return inputs, nil
+}
+
+// runContext is cancelled by an interrupt or after timeout, if not 0
+func runContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// createOutput opens the file at path or returns stdout for an empty path.
+// close may be called more than once.
+func createOutput(path string) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	var closeErr error
+	return file, func() error {
+		once.Do(func() { closeErr = file.Close() })
+		return closeErr
+	}, nil
+}
+
+// severityOf returns the severity of a --level value, 0 for none
+func severityOf(level string) (int, error) {
+	if level == "" {
+		return 0, nil
+	}
+	if severity := parser.Severity(strings.ToUpper(level)); severity != 0 {
+		return severity, nil
+	}
+	return 0, fmt.Errorf("unknown level %s", level)
+}
+
+// grepSource writes the rows of view as src adds entries to it and returns
+// the number of matches. The source stops after limit matches and their
+// after-context, 0 reads it to its end.
+func grepSource(ctx context.Context, src source.Source, view *viewer.Viewer, out export.Writer, limit int, silent bool) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	matched := 0
+	// after-context rows of the last match still written past the limit
+	after := 0
+	var writeErr error
+
+	write := func(s *viewer.State, rows []viewer.Row) {
+		for _, row := range rows {
+			if limit > 0 && matched >= limit {
+				if row.Kind != viewer.ContextRow || after == 0 {
+					after = 0
+					break
+				}
+				after--
+			} else if row.Kind == viewer.MatchRow {
+				matched++
+				if matched == limit && !silent {
+					after = s.After
+				}
+			}
+			if silent || writeErr != nil {
+				continue
+			}
+
+			if row.Kind == viewer.SeparatorRow {
+				writeErr = out.Separator()
+				continue
+			}
+			log, _ := s.Store.Get(row.Pos)
+			writeErr = out.Write(log, row.Kind == viewer.ContextRow)
+		}
+		if writeErr == nil && !silent {
+			writeErr = out.Flush()
+		}
+		if writeErr != nil || (limit > 0 && matched >= limit && after == 0) {
+			cancel()
+		}
+	}
+
+	emit := func(entries []model.LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for len(entries) > 0 && ctx.Err() == nil {
+			batch := entries[:min(len(entries), grepBatch)]
+			entries = entries[len(batch):]
+
+			result := view.Add(batch)
+			view.Do(func(s *viewer.State) {
+				write(s, s.Rows[len(s.Rows)-result.Shown:])
+			})
+		}
+	}
+
+	err := src.Run(ctx, emit, printStatus)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if writeErr != nil {
+		return matched, writeErr
+	}
+	// stopped by the limit, an interrupt or the timeout
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		err = nil
+	}
+	return matched, err
+}
+
+// printStatus reports warnings and errors of a source on stderr
+func printStatus(st source.Status) {
+	if st.Progress != nil || st.Level == "INFO" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", st.Source, st.Message)
+}
+
+// useColor resolves --color, auto colors a terminal unless NO_COLOR is set
+func useColor(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		file, ok := w.(*os.File)
+		return ok && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(file.Fd())), nil
+	}
+	return false, fmt.Errorf("invalid --color %q, expected auto, always or never", mode)
+}
+
+// newestTime returns the newest time among the last lines of the file,
+// now when there is none
+func newestTime(path string) time.Time {
+	latest := time.Now()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return latest
+	}
+	defer file.Close()
+
+	offset := int64(0)
+	if info, err := file.Stat(); err == nil && info.Size() > tailSize {
+		offset = info.Size() - tailSize
+	}
+	lines := bufio.NewScanner(io.NewSectionReader(file, offset, tailSize))
+	lines.Buffer(make([]byte, 0, 64<<10), tailSize)
+	if offset > 0 {
+		// the first line is cut
+		lines.Scan()
+	}
+
+	var newest time.Time
+	for lines.Scan() {
+		if t := parser.ParseLogLine(lines.Text(), 0).Time; t.After(newest) {
+			newest = t
+		}
+	}
+	if newest.IsZero() {
+		return latest
+	}
+	return newest
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gofly-cli/internal/export"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/source"
+	"gofly-cli/internal/viewer"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var grepLines = []string{
+	"[2025-12-04 14:30:00] [INFO] start",
+	"[2025-12-04 14:30:01] [INFO] INVITE a",
+	"[2025-12-04 14:30:02] [INFO] trying",
+	"[2025-12-04 14:30:03] [INFO] ringing",
+	"[2025-12-04 14:30:04] [INFO] ok",
+	"[2025-12-04 14:30:05] [INFO] INVITE b",
+	"[2025-12-04 14:30:06] [INFO] bye",
+	"[2025-12-04 14:30:07] [ERROR] INVITE c failed",
+	"[2025-12-04 14:30:08] [INFO] done",
+}
+
+// grepOutput returns the lines with the numbers, -1 for a separator
+func grepOutput(numbers ...int) string {
+	var out strings.Builder
+	for _, n := range numbers {
+		if n == -1 {
+			out.WriteString("--\n")
+		} else {
+			out.WriteString(grepLines[n] + "\n")
+		}
+	}
+	return out.String()
+}
+
+func TestGrepSource(t *testing.T) {
+	tests := []struct {
+		name          string
+		invert        bool
+		before, after int
+		limit         int
+		silent        bool
+		matched       int
+		want          string
+	}{
+		{name: "matches", matched: 3, want: grepOutput(1, 5, 7)},
+		{name: "after context", after: 1, matched: 3, want: grepOutput(1, 2, -1, 5, 6, 7, 8)},
+		{name: "before context", before: 1, matched: 3, want: grepOutput(0, 1, -1, 4, 5, 6, 7)},
+		{name: "limit", limit: 2, matched: 2, want: grepOutput(1, 5)},
+		{name: "after context of the last match", limit: 1, after: 2, matched: 1, want: grepOutput(1, 2, 3)},
+		{name: "after context up to the next match", limit: 1, after: 5, matched: 1, want: grepOutput(1, 2, 3, 4)},
+		{name: "limit with separators", limit: 2, after: 1, matched: 2, want: grepOutput(1, 2, -1, 5, 6)},
+		{name: "invert", invert: true, matched: 6, want: grepOutput(0, 2, 3, 4, 6, 8)},
+		{name: "silent", silent: true, after: 1, matched: 3},
+		{name: "silent limit", silent: true, limit: 1, after: 1, matched: 1},
+	}
+
+	matcher, err := filter.Compile("invite", filter.ModeText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Join(grepLines, "\n") + "\n"
+
+	for _, tt := range tests {
+		// all lines in one batch, then one batch per line
+		for _, oneByOne := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/one by one %v", tt.name, oneByOne), func(t *testing.T) {
+				view, err := viewer.New(viewer.Options{MaxEntries: grepWindow, Stream: true})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer view.Close()
+				view.Do(func(s *viewer.State) {
+					if tt.invert {
+						s.Filters.Exclusions.Add(matcher)
+					} else {
+						s.Filters.Text = matcher.Expr
+						s.Filters.Matcher = matcher
+					}
+					s.Before, s.After = tt.before, tt.after
+				})
+
+				var r io.Reader = strings.NewReader(text)
+				if oneByOne {
+					r = iotest.OneByteReader(r)
+				}
+				var buf bytes.Buffer
+				out := export.NewWriter(&buf, export.Plain, export.Options{})
+				matched, err := grepSource(context.Background(), source.NewReader("test", r), view, out, tt.limit, tt.silent)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := out.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				if matched != tt.matched {
+					t.Errorf("matched %d, want %d", matched, tt.matched)
+				}
+				if buf.String() != tt.want {
+					t.Errorf("output:\n%s\nwant:\n%s", buf.String(), tt.want)
+				}
+			})
+		}
+	}
+}
+
+// grepFiles writes the logs to files and returns their paths
+func grepFiles(t *testing.T, logs ...[]string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, lines := range logs {
+		path := filepath.Join(dir, fmt.Sprintf("%d.log", i+1))
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestGrepCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	files := grepFiles(t, grepLines, grepLines[:5])
+	missing := filepath.Join(t.TempDir(), "missing.log")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"match", []string{"grep", "ringing", files[0]}, 0, grepOutput(3)},
+		{"no match", []string{"grep", "cancel", files[0]}, 1, ""},
+		{"missing file", []string{"grep", "invite", missing}, 2, ""},
+		{"invalid regex", []string{"grep", "-E", "(", files[0]}, 2, ""},
+		{"unknown level", []string{"grep", "--level", "loud", "invite", files[0]}, 2, ""},
+		{"count", []string{"grep", "-c", "invite", files[0]}, 0, "3\n"},
+		{"count of several inputs", []string{"grep", "-c", "invite", files[0], files[1]}, 0,
+			files[0] + ":3\n" + files[1] + ":1\n"},
+		{"count without matches", []string{"grep", "-c", "cancel", files[0], files[1]}, 1,
+			files[0] + ":0\n" + files[1] + ":0\n"},
+		{"quiet", []string{"grep", "-q", "invite", files[1], files[0]}, 0, ""},
+		{"quiet without matches", []string{"grep", "-q", "cancel", files[0]}, 1, ""},
+		{"max count of several inputs", []string{"grep", "-m", "1", "invite", files[0], files[1]}, 0, grepOutput(1, 1)},
+		{"invert", []string{"grep", "-v", "-x", "INFO", "invite", files[0]}, 1, ""},
+		{"level", []string{"grep", "--level", "error", "invite", files[0]}, 0, grepOutput(7)},
+		{"context", []string{"grep", "-C", "1", "ringing", files[0]}, 0, grepOutput(2, 3, 4)},
+		{"cat", []string{"cat", "--last", "2s", files[0]}, 0, grepOutput(6, 7, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out")
+			args := append([]string{"--no-index", "-o", output, "--color", "never"}, tt.args...)
+			if code := runCommand(args); code != tt.code {
+				t.Errorf("exit code %d, want %d", code, tt.code)
+			}
+			got, _ := os.ReadFile(output)
+			if string(got) != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/config"
+	"gofly-cli/internal/filter"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	filterHistory []string
+	// position while browsing history with Up/Down, len(filterHistory) when not browsing
+	historyPos   int
+	historyDraft string
+
+	savedFilters *config.Filters
+)
+
+func initFilterHistory(filtersFile string) error {
+	filterHistory = config.LoadHistory()
+	historyPos = len(filterHistory)
+
+	path, err := config.FiltersPath(filtersFile)
+	if err != nil {
+		return err
+	}
+
+	savedFilters, err = config.LoadFilters(path)
+	return err
+}
+
+func rememberFilter(text string) {
+	filterHistory, _ = config.AddHistory(filterHistory, text)
+	historyPos = len(filterHistory)
+}
+
+// recallHistory replaces the filter input with an older or newer history entry,
+// the text typed before browsing is restored past the newest entry
+func recallHistory(older bool) {
+	if len(filterHistory) == 0 {
+		return
+	}
+
+	if historyPos == len(filterHistory) {
+		historyDraft = input.GetText()
+	}
+
+	if older {
+		if historyPos == 0 {
+			return
+		}
+		historyPos--
+	} else {
+		if historyPos == len(filterHistory) {
+			return
+		}
+		historyPos++
+	}
+
+	if historyPos == len(filterHistory) {
+		input.SetText(historyDraft)
+	} else {
+		input.SetText(filterHistory[historyPos])
+	}
+}
+
+func filterInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyUp:
+		recallHistory(true)
+		return nil
+	case tcell.KeyDown:
+		recallHistory(false)
+		return nil
+	case tcell.KeyCtrlS:
+		showSaveFilterPrompt()
+		return nil
+	}
+	return event
+}
+
+// storedFilter keeps the regex mode in the text itself, so saved filters
+// work regardless of the mode they are applied in
+func storedFilter(text string) string {
+	text = strings.TrimSpace(text)
+	if filterMode != filter.ModeRegex || strings.HasPrefix(text, filter.RegexPrefix) {
+		return text
+	}
+	if len(text) > 2 && text[0] == '/' && text[len(text)-1] == '/' {
+		return text
+	}
+	return filter.RegexPrefix + text
+}
+
+func showSaveFilterPrompt() {
+	text := storedFilter(input.GetText())
+	if text == "" {
+		return
+	}
+
+	showPrompt(" Save Filter "+tview.Escape(text)+" ", "Name: ", "", func(name string) error {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("name is required")
+		}
+
+		savedFilters.Set(name, text)
+		return savedFilters.Save()
+	})
+}
+
+// showSavedFilters lists saved filters, Enter applies the selected one
+func showSavedFilters() {
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" Saved Filters [%s] ", tview.Escape(savedFilters.Path)))
+
+	hint := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]Enter[-] Apply   [yellow]d[-] Delete   [yellow]Esc[-] Close   [yellow]Ctrl-S[-] in the filter input saves it")
+
+	refresh := func() {
+		list.Clear()
+		for _, saved := range savedFilters.Filters {
+			list.AddItem(tview.Escape(saved.Name), tview.Escape(saved.Filter), 0, nil)
+		}
+	}
+
+	list.SetSelectedFunc(func(i int, _ string, _ string, _ rune) {
+		text := savedFilters.Filters[i].Filter
+		closeDialog()
+		setInputText(text)
+		setFilter(text)
+		rememberFilter(text)
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEsc:
+			closeDialog()
+			return nil
+		case event.Rune() == 'd':
+			savedFilters.Remove(list.GetCurrentItem())
+			if err := savedFilters.Save(); err != nil {
+				hint.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+			}
+			refresh()
+			return nil
+		}
+		return event
+	})
+
+	refresh()
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(list, 0, 1, true).
+		AddItem(hint, 1, 0, false)
+
+	showDialog(layout, list)
+}
+
+// setInputText sets the text of the filter input without applying it, the
+// caller does
+func setInputText(text string) {
+	if searchTimer != nil {
+		searchTimer.Stop()
+		isSearching = false
+	}
+	settingInput = true
+	input.SetText(text)
+	settingInput = false
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/viewer"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const ladderColWidth = 26
+
+// showLadder draws the SIP sequence diagram of a call,
+// Enter jumps to the log row of the selected message
+func showLadder(callID string) {
+	var ladder *calls.Ladder
+	view.Do(func(s *viewer.State) {
+		if call := s.Calls.Get(callID); call != nil {
+			ladder = calls.BuildLadder(s.Store, call.Entries)
+		}
+	})
+	if ladder == nil {
+		return
+	}
+
+	table := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Ladder: %s ", tview.Escape(callID)))
+
+	for i, h := range []string{"Time", "Delta", ladder.Header(ladderColWidth)} {
+		table.SetCell(0, i, tview.NewTableCell(tview.Escape(h)).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+
+	var prev time.Time
+	for i, arrow := range ladder.Arrows {
+		timeText := ""
+		delta := ""
+		if !arrow.Time.IsZero() {
+			timeText = arrow.Time.Format("15:04:05.000")
+			if !prev.IsZero() {
+				delta = fmt.Sprintf("+%.3fs", arrow.Time.Sub(prev).Seconds())
+			}
+			prev = arrow.Time
+		}
+
+		color := tcell.ColorWhite
+		if !arrow.Message.IsRequest {
+			color = finalColor(arrow.Message.StatusCode)
+			if arrow.Message.StatusCode < 200 {
+				color = tcell.ColorGray
+			}
+		}
+
+		table.SetCell(i+1, 0, tview.NewTableCell(timeText))
+		table.SetCell(i+1, 1, tview.NewTableCell(delta).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 2, tview.NewTableCell(tview.Escape(ladder.Render(arrow, ladderColWidth))).
+			SetTextColor(color))
+	}
+
+	if len(ladder.Arrows) == 0 {
+		table.SetCell(1, 2, tview.NewTableCell("No SIP messages found for this call").
+			SetTextColor(tcell.ColorGray))
+	} else {
+		table.Select(1, 0)
+	}
+
+	table.SetSelectedFunc(func(row, column int) {
+		if row < 1 || row > len(ladder.Arrows) {
+			return
+		}
+		jumpToEntry(ladder.Arrows[row-1].Pos, callID)
+	})
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeDialog()
+			return nil
+		}
+		return event
+	})
+
+	hint := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]Enter[-] Jump to log row   [yellow]Esc[-] Close")
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(hint, 1, 0, false)
+
+	showDialog(layout, table)
+}
+
+// showSelectedLadder opens the ladder of the call of the selected log row
+func showSelectedLadder() {
+	row, _ := logTable.GetSelection()
+	if log, ok := rowEntry(row); ok && log.CallID != "" {
+		showLadder(log.CallID)
+	}
+}
+
+// jumpToEntry selects the row of the stored entry at pos. When filters hide
+// it, the table is limited to its call first.
+func jumpToEntry(pos int, callID string) {
+	closeDialog()
+
+	row := findRow(pos)
+	if row == -1 {
+		setCallFilter(callID)
+		row = findRow(pos)
+	}
+	if row != -1 {
+		logTable.Select(row, 0)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/viewer"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var levelText *tview.TextView
+
+// cycleMinLevel raises the level threshold step by step and wraps back to ALL
+func cycleMinLevel() {
+	view.Do(func(s *viewer.State) {
+		next := 0
+		for _, severity := range parser.Severities() {
+			if severity > s.Filters.MinSeverity {
+				next = severity
+				break
+			}
+		}
+		s.Filters.MinSeverity = next
+	})
+
+	rebuildTable()
+	updateLevelStatus()
+}
+
+// toggleLevel hides or shows the n-th level of parser.Levels (0-based)
+func toggleLevel(n int) {
+	if n < 0 || n >= len(parser.Levels) {
+		return
+	}
+
+	name := parser.Levels[n].Name
+	view.Do(func(s *viewer.State) {
+		s.Filters.Hidden[name] = !s.Filters.Hidden[name]
+	})
+
+	rebuildTable()
+	updateLevelStatus()
+}
+
+func updateLevelStatus() {
+	var text strings.Builder
+
+	view.Do(func(s *viewer.State) {
+		f := &s.Filters
+		if f.MinSeverity == 0 {
+			text.WriteString("Level: ALL   ")
+		} else {
+			fmt.Fprintf(&text, "Level: >=%s   ", parser.SeverityName(f.MinSeverity))
+		}
+
+		for i, level := range parser.Levels {
+			color := colorTag(level.Color)
+			if !s.LevelVisible(level.Name) {
+				color = "gray"
+			}
+			fmt.Fprintf(&text, "   [gray]%d:[-][%s]%s[-] %d", i+1, color, level.Name, s.LevelCounts[level.Name])
+		}
+
+		if !f.TimeRange.IsZero() {
+			fmt.Fprintf(&text, "      Time: [aqua]%s[-]", f.TimeRange)
+		}
+
+		if len(f.Calls) == 1 {
+			for id := range f.Calls {
+				fmt.Fprintf(&text, "      Call: [aqua]%s[-]", tview.Escape(id))
+			}
+		} else if len(f.Calls) > 1 {
+			fmt.Fprintf(&text, "      Calls: [aqua]%d[-]", len(f.Calls))
+		}
+
+		if s.ContextEnabled() {
+			fmt.Fprintf(&text, "      Context: -B%d -A%d", s.Before, s.After)
+		}
+	})
+
+	levelText.SetText(text.String())
+}
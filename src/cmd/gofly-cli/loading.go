@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/loader"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/source"
+	"gofly-cli/internal/viewer"
+	"strings"
+	"time"
+)
+
+const progressBarWidth = 20
+
+var (
+	// cancels the running file load, nil when nothing is loading
+	cancelLoad   context.CancelFunc
+	lastProgress time.Time
+
+	// sidecar index of the loaded file, nil when there is none
+	fileIndex *index.Index
+	indexPath string
+)
+
+// runSource reads src in the background and calls done on the UI goroutine
+// when it ends or is stopped by the returned function
+func runSource(src source.Source, done func(err error)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		err := src.Run(ctx, addLogs, showStatus)
+
+		app.QueueUpdateDraw(func() {
+			cancel()
+			done(err)
+		})
+	}()
+
+	return cancel
+}
+
+// loadFile reads the file like any source, its loading can be cancelled and
+// its index is kept for searching dropped entries. With an index only the
+// lines fitting the memory budget are read.
+func loadFile(path string, maxEntries int, maxBytes int64, done func()) {
+	file := source.NewFile(path)
+	file.Options = loader.Options{
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+		OnIndex: func(idx *index.Index, skipped int) {
+			view.Do(func(s *viewer.State) {
+				s.UseFileIndex(idx, skipped)
+			})
+		},
+	}
+	cancelLoad = runSource(file, func(err error) {
+		cancelLoad = nil
+		if err == nil {
+			fileIndex, indexPath = file.Index(), path
+		}
+		done()
+	})
+}
+
+func cancelLoading() {
+	if cancelLoad != nil {
+		cancelLoad()
+	}
+}
+
+// showStatus shows the progress of loading files in the status bar, other
+// events of sources are added to the log
+func showStatus(st source.Status) {
+	if st.Progress != nil {
+		showLoadProgress(*st.Progress)
+		return
+	}
+
+	entry := parser.ParseLogLine(st.Line(), 0)
+	entry.Source = st.Source
+	addLogs([]model.LogEntry{entry})
+}
+
+// showLoadProgress limits redraws to 10 per second
+func showLoadProgress(p loader.Progress) {
+	if !p.Done && time.Since(lastProgress) < 100*time.Millisecond {
+		return
+	}
+	lastProgress = time.Now()
+
+	app.QueueUpdateDraw(func() {
+		progressText.SetText(formatProgress(p))
+	})
+}
+
+func formatProgress(p loader.Progress) string {
+	percent := int(p.Fraction() * 100)
+
+	switch {
+	case errors.Is(p.Err, context.Canceled):
+		return fmt.Sprintf("[yellow]Loading cancelled at %d%%, %d lines[-]", percent, p.Lines)
+	case p.Err != nil:
+		return fmt.Sprintf("[red]Loading failed: %s[-]", p.Err)
+	case p.Done && p.Indexed && p.Skipped > 0:
+		return fmt.Sprintf("[gray]Loaded the last %d of %d lines in %s from index[-]",
+			p.Lines, p.Skipped+p.Lines, p.Elapsed.Round(time.Millisecond))
+	case p.Done && p.Indexed:
+		return fmt.Sprintf("[gray]Loaded %d lines in %s from index[-]", p.Lines, p.Elapsed.Round(time.Millisecond))
+	case p.Done:
+		return fmt.Sprintf("[gray]Loaded %d lines in %s[-]", p.Lines, p.Elapsed.Round(time.Millisecond))
+	}
+
+	filled := int(p.Fraction() * progressBarWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+
+	return fmt.Sprintf("[green]%s[-] %3d%%  %s/%s  %s lines/s  ETA %s  [yellow]F10[-] Cancel",
+		bar, percent, formatBytes(p.Bytes), formatBytes(p.Total),
+		formatCount(p.LinesPerSecond()), p.ETA().Round(time.Second))
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+func formatCount(n float64) string {
+	switch {
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", n/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.0fk", n/1e3)
+	}
+	return fmt.Sprintf("%.0f", n)
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
-	"bufio"
-	"flag"
 	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/loader"
 	"gofly-cli/internal/model"
-	"gofly-cli/internal/parser"
-	"net"
+	"gofly-cli/internal/source"
+	"gofly-cli/internal/store"
+	"gofly-cli/internal/viewer"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,13 +19,20 @@ import (
 )
 
 var (
-	appVersion  = "25.12.4"
-	activeLogs  int
-	tsRegexp    = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\]`)
-	hotBar      *tview.TextView
-	input       *tview.InputField
-	searchTimer *time.Timer
-	isSearching bool
+	appVersion = "25.12.4"
+	tsRegexp   = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\]`)
+	hotBar     *tview.TextView
+	exprText   *tview.TextView
+
+	searchInput  *tview.InputField
+	searchStatus *tview.TextView
+	progressText *tview.TextView
+	input        *tview.InputField
+	filterLabel  *tview.TextView
+	searchTimer  *time.Timer
+	isSearching  bool
+	// set while the filter input is changed by code that applies the filter itself
+	settingInput bool
 
 	logTable   *tview.Table
 	statusBar  *tview.Flex
@@ -31,44 +40,71 @@ var (
 	flex       *tview.Flex
 	serverAddr string
 	// хранения всех логов и фильтра
-	allLogs       []model.LogEntry
-	currentFilter string
-	currentMode   string
-	inputFile     string
-	// буфер для обработки файлов
-	logBatch  []model.LogEntry
-	batchSize = 100
-
-	sessionColors = make(map[string]tcell.Color)
-	colorIndex    = 0
-
-	lastSubAck    = time.Now()
-	isConnected   = false
-	alreadyWarned = false
+	view        *viewer.Viewer
+	filterMode  = filter.ModeText
+	filterError string
+	currentMode string
+	inputFile   string
 
 	autoScroll = false
+	mainActive = true
 )
 
 func main() {
-	ip := flag.String("ip", "127.0.0.1", "Set custom server IP. [-ip %host%]")
-	port := flag.String("port", "9090", "Set custom server PORT. [-port %port%]")
-	help := flag.Bool("h", false, "Prints flags and their descriptions")
-	inputFilePtr := flag.String("I", "", "Change the mod of app from connect and reading UDP to parse the FILE. [-I %path to file%]")
-	flag.Parse()
+	os.Exit(runCommand(os.Args[1:]))
+}
 
-	if *help {
-		fmt.Println("gofly-cli — CLI for gofly")
-		fmt.Println("Usage: gofly-cli [-h | -I filename | [-ip host][-port port]]")
-		flag.PrintDefaults()
-		os.Exit(0)
+// runView starts the interactive viewer on a file, stdin or the server
+func runView(opts *options) error {
+	budget, err := store.ParseSize(opts.maxMemory)
+	if err != nil {
+		return fmt.Errorf("--max-memory: %w", err)
+	}
+	loader.UseIndex = !opts.noIndex
+	if opts.context > 0 {
+		opts.before, opts.after = opts.context, opts.context
+	}
+	minSeverity, err := severityOf(opts.level)
+	if err != nil {
+		return err
+	}
+	var exclusions []*filter.Matcher
+	for _, expr := range opts.exclude {
+		m, err := filter.Compile(expr, filterMode)
+		if err != nil {
+			return fmt.Errorf("--exclude %s: %w", expr, err)
+		}
+		exclusions = append(exclusions, m)
 	}
 
-	serverAddr = fmt.Sprintf("%s:%s", *ip, *port)
-	inputFile = *inputFilePtr
-	allLogs = make([]model.LogEntry, 0)
-	logBatch = make([]model.LogEntry, 0, batchSize)
+	if err := initFilterHistory(opts.filters); err != nil {
+		return fmt.Errorf("failed to load saved filters: %w", err)
+	}
 
-	if inputFile != "" {
+	serverAddr = fmt.Sprintf("%s:%s", opts.ip, opts.port)
+	inputFile = opts.input
+	view, err = viewer.New(viewer.Options{
+		MaxEntries: opts.maxEntries,
+		MaxBytes:   budget,
+		Spill:      opts.spill,
+	})
+	if err != nil {
+		return err
+	}
+	view.Do(func(s *viewer.State) {
+		s.Before, s.After = opts.before, opts.after
+		s.Filters.MinSeverity = minSeverity
+		for _, id := range opts.calls {
+			s.Filters.Calls[id] = true
+		}
+		for _, m := range exclusions {
+			s.Filters.Exclusions.Add(m)
+		}
+	})
+
+	if inputFile == "-" {
+		currentMode = "Stdin"
+	} else if inputFile != "" {
 		currentMode = fmt.Sprintf("File [%s]", inputFile)
 	} else {
 		currentMode = "Online"
@@ -89,31 +125,40 @@ func main() {
 	// Set title by mode
 	if inputFile != "" {
 		infoText.SetText(fmt.Sprintf(
-			"gofly-cli v.%s    Current Mode: %s    Logs: %d    Match Expressions: 0",
-			appVersion, currentMode, activeLogs))
+			"gofly-cli v.%s    Current Mode: %s    Logs: 0",
+			appVersion, currentMode))
 	} else {
 		infoText.SetText(fmt.Sprintf(
-			"gofly-cli v.%s    Current Mode: %s    [%s]    Logs: %d    Match Expressions: 0",
-			appVersion, currentMode, serverAddr, activeLogs))
+			"gofly-cli v.%s    Current Mode: %s    [%s]    Logs: 0",
+			appVersion, currentMode, serverAddr))
 	}
 
-	firstLine.AddItem(infoText, 0, 1, false)
+	progressText = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignRight)
+
+	firstLine.
+		AddItem(infoText, 0, 1, false).
+		AddItem(progressText, 0, 1, false)
 
 	// Second row - filter
 	secondLine := tview.NewFlex().SetDirection(tview.FlexColumn)
 
-	filterLabel := tview.NewTextView().
-		SetDynamicColors(true).
-		SetText("Display Filter:")
+	filterLabel = tview.NewTextView().
+		SetDynamicColors(true)
+	updateFilterLabel()
 
 	input = tview.NewInputField().
 		SetPlaceholder("Enter filter text...").
 		SetFieldWidth(50).
 		SetChangedFunc(func(text string) {
+			if settingInput {
+				return
+			}
 
 			if !isSearching && text != "" {
 				isSearching = true
-				updateStatusBar(len(allLogs), "typing...")
+				updateStatusBar()
 			}
 
 			if searchTimer != nil {
@@ -123,12 +168,7 @@ func main() {
 			searchTimer = time.AfterFunc(750*time.Millisecond, func() {
 				app.QueueUpdateDraw(func() {
 					isSearching = false
-					currentFilter = strings.TrimSpace(text)
-					if currentFilter != "" {
-						applyFilter(currentFilter)
-					} else {
-						clearFilter()
-					}
+					setFilter(text)
 				})
 			})
 		}).
@@ -140,34 +180,66 @@ func main() {
 
 			switch key {
 			case tcell.KeyEnter:
-				currentFilter = strings.TrimSpace(input.GetText())
-				if currentFilter != "" {
-					applyFilter(currentFilter)
-				} else {
-					clearFilter()
-				}
+				setFilter(input.GetText())
+				rememberFilter(input.GetText())
 				app.SetFocus(logTable)
 			case tcell.KeyEsc:
-				input.SetText("")
+				setInputText("")
 				clearFilter()
 				app.SetFocus(logTable)
 			}
 		})
 
+	searchInput = tview.NewInputField().
+		SetLabel("Search: ").
+		SetPlaceholder("/ to search, n/N to navigate").
+		SetFieldWidth(40).
+		SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				setSearch(searchInput.GetText())
+			case tcell.KeyEsc:
+				searchInput.SetText("")
+				setSearch("")
+			}
+			app.SetFocus(logTable)
+		})
+
+	searchStatus = tview.NewTextView().
+		SetDynamicColors(true)
+
+	input.SetInputCapture(filterInputCapture)
+
 	secondLine.
 		AddItem(filterLabel, 15, 1, false).
-		AddItem(input, 50, 1, false)
+		AddItem(input, 50, 1, false).
+		AddItem(nil, 4, 0, false).
+		AddItem(searchInput, 48, 0, false).
+		AddItem(searchStatus, 0, 1, false)
+
+	// Third row - match expressions
+	exprText = tview.NewTextView().
+		SetDynamicColors(true)
+	updateExprStatus()
+
+	// Fourth row - levels
+	levelText = tview.NewTextView().
+		SetDynamicColors(true)
+	updateLevelStatus()
 
 	// build the status bar
 	statusBar.
 		AddItem(firstLine, 1, 1, false).
-		AddItem(secondLine, 1, 1, false)
+		AddItem(secondLine, 1, 1, false).
+		AddItem(exprText, 1, 1, false).
+		AddItem(levelText, 1, 1, false)
 
 	statusBar.SetBorder(true).SetTitle(" Status ")
 
 	//  Log table
 	logTable = tview.NewTable().
 		SetBorders(false).
+		SetContent(logRows).
 		SetFixed(1, 0)
 
 	headers := []string{"Idx", "Time", "Level", "Message"}
@@ -188,7 +260,7 @@ func main() {
 			cell.SetExpansion(1)
 		}
 
-		logTable.SetCell(0, i, cell)
+		logRows.header = append(logRows.header, cell)
 	}
 	logTable.SetSelectable(true, false)
 
@@ -196,560 +268,540 @@ func main() {
 	hotBar = tview.NewTextView().SetDynamicColors(true)
 	hotBar.
 		SetDynamicColors(true).
-		SetText("[yellow]Esc\\Q[-] Quit   [green]F1[-] Help   [green]F3[-] Focus Filter   [green]F4[-] Clear Filter   [green]F5[-] Clear   [red]F6[-] AutoScroll")
-	hotBar.SetBorder(true)
+		SetBorder(true)
+	updateHotBar(hotBar)
 	hotBar.SetTitle(" Hotkeys ")
 
+	initDetailPane()
+
 	flex = tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(statusBar, 4, 1, false).
-		AddItem(logTable, 0, 1, true).
+		AddItem(statusBar, 6, 1, false).
+		AddItem(content, 0, 1, true).
 		AddItem(hotBar, 3, 1, false)
 
 	//  hot tabs
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// dialogs handle their own keys
+		if !mainActive {
+			return event
+		}
+
 		switch event.Key() {
+		case tcell.KeyTab:
+			// move focus between the table and the detail pane to scroll it
+			if detailVisible && app.GetFocus() == logTable {
+				app.SetFocus(detailView)
+				return nil
+			}
+			if app.GetFocus() == detailView {
+				app.SetFocus(logTable)
+				return nil
+			}
 		case tcell.KeyEsc:
+			if app.GetFocus() == detailView {
+				app.SetFocus(logTable)
+				return nil
+			}
+			if app.GetFocus() == searchInput {
+				return event
+			}
 			if app.GetFocus() == input {
 				input.SetText("")
 				app.SetFocus(logTable)
 			} else {
 				app.Stop()
+				view.Close()
 				os.Exit(0)
 			}
 		case tcell.KeyF1:
 			showHelp()
+		case tcell.KeyF2:
+			cycleMinLevel()
 		case tcell.KeyF3:
 			app.SetFocus(input)
 		case tcell.KeyF4:
-			input.SetText("")
+			setInputText("")
+			clearCallFilter()
 			clearFilter()
 		case tcell.KeyF5:
 			clearLogs()
 		case tcell.KeyF6:
 			autoScroll = !autoScroll
-			updateHotBar(hotBar, autoScroll)
-			app.Draw()
+			updateHotBar(hotBar)
+		case tcell.KeyF7:
+			toggleFilterMode()
+		case tcell.KeyF8:
+			showExpressions()
+		case tcell.KeyF9:
+			showExclusions()
+		case tcell.KeyF10:
+			cancelLoading()
+		}
+
+		// keys below are plain characters, let the inputs have them
+		if app.GetFocus() == input || app.GetFocus() == searchInput {
+			return event
 		}
 
 		switch event.Rune() {
 		case 'q', 'Q':
-			if app.GetFocus() != input {
-				app.Stop()
-				os.Exit(0)
-				return nil
-			}
+			app.Stop()
+			view.Close()
+			os.Exit(0)
+			return nil
+		case '/':
+			app.SetFocus(searchInput)
+			return nil
+		case 'n':
+			searchNext(true)
+			return nil
+		case 'N':
+			searchNext(false)
+			return nil
+		case 't':
+			showTimeRangePrompt()
+			return nil
+		case 'g':
+			showPrompt(" Jump To Time ", "Time: ", "", jumpToTime)
+			return nil
+		case 'c':
+			showContextPrompt()
+			return nil
+		case 'f':
+			showSavedFilters()
+			return nil
+		case 'S':
+			showSaveFilterPrompt()
+			return nil
+		case 'd':
+			toggleDetail()
+			return nil
+		case 'D':
+			toggleDetailSide()
+			return nil
+		case 'l':
+			showCalls()
+			return nil
+		case 'L':
+			showSelectedLadder()
+			return nil
+		case 's':
+			showStats()
+			return nil
+		case 'P':
+			showPcapExport()
+			return nil
+		case 'E':
+			showExport()
+			return nil
+		case 'O':
+			showSpillSearchPrompt()
+			return nil
+		case 'v':
+			showSelectedConversation()
+			return nil
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			toggleLevel(int(event.Rune() - '1'))
+			return nil
 		}
 
 		return event
 	})
 
 	//  from file данных
-	if inputFile != "" {
+	if inputFile == "-" {
+		// a piped log, time options wait for its end like for a file
+		runSource(source.Stdin(), func(error) {
+			applyTimeFlags(opts.from, opts.to, opts.last, opts.jump)
+		})
+	} else if inputFile != "" {
 		if _, err := os.Stat(inputFile); err == nil {
-			go func() {
-				file, err := os.Open(inputFile)
-				if err != nil {
-					panic(err)
-				}
-				defer file.Close()
-
-				scanner := bufio.NewScanner(file)
-				buf := make([]byte, 0, 64*1024)
-				scanner.Buffer(buf, 1024*1024)
-
-				for scanner.Scan() {
-					line := scanner.Text()
-					processLine(line)
-				}
-				if len(logBatch) > 0 {
-					flushLogBatch()
-				}
-			}()
+			// relative ranges need the newest entry, so time options wait for the whole file
+			loadFile(inputFile, opts.maxEntries, budget, func() {
+				applyTimeFlags(opts.from, opts.to, opts.last, opts.jump)
+			})
 		} else {
-			fmt.Printf("File %s not found\n", inputFile)
-			os.Exit(1)
+			return fmt.Errorf("file %s not found", inputFile)
 		}
 	} else {
-		//  UDP mode: real time
-		laddr, _ := net.ResolveUDPAddr("udp", ":0")
-		conn, err := net.ListenUDP("udp", laddr)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to create UDP listener: %v\n", err)
-			return
-		}
-		defer conn.Close()
-
-		serverUDP, err := net.ResolveUDPAddr("udp", serverAddr)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to resolve server address %s: %v\n", serverAddr, err)
-			return
-		}
-
-		// GUI startup
-		appStarted := make(chan bool, 1)
-		go func() {
-			if err := app.SetRoot(flex, true).Run(); err != nil {
-				panic(err)
-			}
-			appStarted <- true
-		}()
-
-		processLineRealtime(logWithTime("INFO", fmt.Sprintf("Connecting to %s", serverAddr)))
-		lastSubAck = time.Now()
-
-		// SUB every 0.5x= sec
-		go func() {
-			ticker := time.NewTicker(500 * time.Millisecond)
-			defer ticker.Stop()
-
-			for {
-				<-ticker.C
-				_, err := conn.WriteToUDP([]byte("SUB"), serverUDP)
-				if err != nil {
-					processLineRealtime(logWithTime("WARN", fmt.Sprintf("SUB send error: %v", err)))
-				}
-
-				if time.Since(lastSubAck) > 1*time.Second {
-					if !isConnected {
-						if !alreadyWarned {
-							processLineRealtime(logWithTime("WARN",
-								fmt.Sprintf("Lost connection to %s", serverAddr)))
-							alreadyWarned = true
-						}
-					}
-					isConnected = false
-				} else {
-					if !isConnected {
-						processLineRealtime(logWithTime("INFO",
-							fmt.Sprintf("Reconnected to %s", serverAddr)))
-					}
-
-					isConnected = true
-					alreadyWarned = false
-				}
-			}
-		}()
-
-		//  Read UDP real time
-		go func() {
-			buf := make([]byte, 4096)
-			for {
-				n, _, err := conn.ReadFromUDP(buf)
-				if err != nil {
-					if strings.Contains(err.Error(), "use of closed network connection") {
-						processLineRealtime("[INFO] UDP connection closed")
-						return
-					}
-					processLineRealtime(fmt.Sprintf("[ERROR] UDP read: %v", err))
-					continue
-				}
-
-				msg := strings.TrimSpace(string(buf[:n]))
-
-				if strings.HasPrefix(msg, "SUB_ACK") {
-					lastSubAck = time.Now()
-
-					if !isConnected {
-						isConnected = true
-						processLineRealtime(logWithTime("INFO", fmt.Sprintf(
-							"Connected. Local: %s, Server: %s",
-							conn.LocalAddr().String(), serverAddr,
-						)))
-
-					}
-					continue
-				}
-
-				processLineRealtime(msg)
-			}
-		}()
-
+		//  UDP mode: real time, failures are shown as log lines
+		runSource(source.NewUDP(serverAddr), func(error) {})
 	}
 
-	if err := app.SetRoot(flex, true).Run(); err != nil {
-		panic(err)
-	}
+	err = app.SetRoot(flex, true).Run()
+	view.Close()
+	return err
 }
 
-func processLine(line string) {
-	logEntry := parser.ParseLogLine(line, len(allLogs)+len(logBatch))
-	logBatch = append(logBatch, logEntry)
-
-	if len(logBatch) >= batchSize {
-		flushLogBatch()
+func clearLogs() {
+	if err := view.Clear(); err != nil {
+		filterError = err.Error()
+	} else {
+		filterError = ""
 	}
+	updateSearchStatus(0)
+	updateExprStatus()
+	updateLevelStatus()
+	setInputText("")
+
+	updateStatusBar()
 }
 
-func processLineRealtime(line string) {
-	logEntry := parser.ParseLogLine(line, len(allLogs))
+// addLogs hands entries to view from any goroutine, keeping the selection
+// on the same entry when old ones are dropped
+func addLogs(entries []model.LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
 
 	app.QueueUpdateDraw(func() {
-		allLogs = append(allLogs, logEntry)
-		activeLogs++
-
-		if currentFilter != "" {
-			searchLower := strings.ToLower(currentFilter)
-
-			if !strings.Contains(strings.ToLower(logEntry.Message), searchLower) &&
-				!strings.Contains(strings.ToLower(logEntry.Timestamp), searchLower) &&
-				!strings.Contains(strings.ToLower(logEntry.Level), searchLower) &&
-				!strings.Contains(strings.ToLower(logEntry.OriginalMessage), searchLower) {
-				updateStatusBar(logTable.GetRowCount()-1, currentFilter)
-				return
-			}
-
-			row := logTable.GetRowCount()
-
-			idxCell := tview.NewTableCell(logEntry.Index)
-			timeCell := tview.NewTableCell("")
-			levelCell := tview.NewTableCell("")
-			msgCell := tview.NewTableCell("")
-
-			highlightSearchText(timeCell, logEntry.Timestamp, currentFilter)
-			highlightSearchText(levelCell, logEntry.Level, currentFilter)
-			highlightSearchText(msgCell, logEntry.Message, currentFilter)
-
-			levelCell.SetTextColor(logEntry.LevelColor).SetAlign(tview.AlignCenter)
-
-			logTable.SetCell(row, 0, idxCell)
-			logTable.SetCell(row, 1, timeCell)
-			logTable.SetCell(row, 2, levelCell)
-			logTable.SetCell(row, 3, msgCell)
-
-			// add style to row
-			setRowStyle(row, idxCell, timeCell, levelCell, msgCell)
-
-			if autoScroll {
-				logTable.Select(row, 0)
-				logTable.ScrollToEnd()
-			}
-
-			displayedCount := logTable.GetRowCount() - 1
-			updateStatusBar(displayedCount, currentFilter)
-			return
+		result := view.Add(entries)
+		if result.Removed > 0 {
+			row, _ := logTable.GetSelection()
+			logTable.Select(max(row-result.Removed, 1), 0)
+			offset, _ := logTable.GetOffset()
+			logTable.SetOffset(max(offset-result.Removed, 0), 0)
 		}
 
-		//  Filter is active
-		row := logTable.GetRowCount()
-
-		idxCell := tview.NewTableCell(logEntry.Index)
-		timeCell := tview.NewTableCell(logEntry.Timestamp)
-		levelCell := tview.NewTableCell(logEntry.Level).
-			SetTextColor(logEntry.LevelColor).
-			SetAlign(tview.AlignCenter)
-		msgCell := tview.NewTableCell(logEntry.Message)
-
-		logTable.SetCell(row, 0, idxCell)
-		logTable.SetCell(row, 1, timeCell)
-		logTable.SetCell(row, 2, levelCell)
-		logTable.SetCell(row, 3, msgCell)
-
-		setRowStyle(row, idxCell, timeCell, levelCell, msgCell)
-
-		updateStatusBar(len(allLogs), "")
+		updateExprStatus()
+		updateLevelStatus()
+		updateStatusBar()
 		if autoScroll {
-			logTable.Select(row, 0)
 			logTable.ScrollToEnd()
 		}
-
 	})
 }
 
-func clearLogs() {
-	for i := logTable.GetRowCount() - 1; i > 0; i-- {
-		logTable.RemoveRow(i)
-	}
-	allLogs = make([]model.LogEntry, 0)
-	logBatch = make([]model.LogEntry, 0, batchSize)
-	activeLogs = 0
-	currentFilter = ""
-	input.SetText("")
-
-	updateStatusBar(0, "")
-}
+// setFilter compiles the display filter once per change and rebuilds the table.
+// An invalid expression keeps the previous filter and reports the error.
+func setFilter(text string) {
+	text = strings.TrimSpace(text)
+	filterError = ""
 
-func flushLogBatch() {
-	if len(logBatch) == 0 {
+	if text == "" {
+		clearFilter()
 		return
 	}
 
-	app.QueueUpdateDraw(func() {
-		allLogs = append(allLogs, logBatch...)
-		activeLogs += len(logBatch)
-
-		if currentFilter != "" {
-			searchLower := strings.ToLower(currentFilter)
-			for _, log := range logBatch {
-				if strings.Contains(strings.ToLower(log.Message), searchLower) ||
-					strings.Contains(strings.ToLower(log.Timestamp), searchLower) ||
-					strings.Contains(strings.ToLower(log.Level), searchLower) ||
-					strings.Contains(strings.ToLower(log.OriginalMessage), searchLower) {
-
-					row := logTable.GetRowCount()
-
-					idxCell := tview.NewTableCell(log.Index)
-					timeCell := tview.NewTableCell("")
-					levelCell := tview.NewTableCell("")
-					msgCell := tview.NewTableCell("")
-
-					highlightSearchText(timeCell, log.Timestamp, currentFilter)
-					highlightSearchText(levelCell, log.Level, currentFilter)
-					highlightSearchText(msgCell, log.Message, currentFilter)
-
-					levelCell.SetTextColor(log.LevelColor).SetAlign(tview.AlignCenter)
+	matcher, err := filter.Compile(text, filterMode)
+	if err != nil {
+		filterError = err.Error()
+		updateStatusBar()
+		return
+	}
 
-					logTable.SetCell(row, 0, idxCell)
-					logTable.SetCell(row, 1, timeCell)
-					logTable.SetCell(row, 2, levelCell)
-					logTable.SetCell(row, 3, msgCell)
+	view.Do(func(s *viewer.State) {
+		s.Filters.Text = text
+		s.Filters.Matcher = matcher
+	})
+	applyFilter()
+}
 
-					setRowStyle(row, idxCell, timeCell, levelCell, msgCell)
-				}
-			}
-			displayedCount := logTable.GetRowCount() - 1
-			updateStatusBar(displayedCount, currentFilter)
-		} else {
-			for _, log := range logBatch {
-				row := logTable.GetRowCount()
-
-				idxCell := tview.NewTableCell(log.Index)
-				timeCell := tview.NewTableCell(log.Timestamp)
-				levelCell := tview.NewTableCell(log.Level).
-					SetTextColor(log.LevelColor).
-					SetAlign(tview.AlignCenter)
-				msgCell := tview.NewTableCell(log.Message)
-
-				logTable.SetCell(row, 0, idxCell)
-				logTable.SetCell(row, 1, timeCell)
-				logTable.SetCell(row, 2, levelCell)
-				logTable.SetCell(row, 3, msgCell)
-
-				setRowStyle(row, idxCell, timeCell, levelCell, msgCell)
-			}
-			updateStatusBar(len(allLogs), "")
-			if autoScroll {
-				logTable.ScrollToEnd()
-			}
+func toggleFilterMode() {
+	if filterMode == filter.ModeRegex {
+		filterMode = filter.ModeText
+	} else {
+		filterMode = filter.ModeRegex
+	}
 
-		}
+	updateFilterLabel()
+	updateHotBar(hotBar)
 
-		logBatch = logBatch[:0]
-	})
+	if strings.TrimSpace(input.GetText()) != "" {
+		setFilter(input.GetText())
+	}
 }
 
-func applyFilter(searchText string) {
-	for i := logTable.GetRowCount() - 1; i > 0; i-- {
-		logTable.RemoveRow(i)
-	}
+func applyFilter() {
+	displayedLogs := rebuildTable()
 
-	displayedLogs := 0
-	searchLower := strings.ToLower(searchText)
+	if displayedLogs > 0 {
+		logTable.Select(1, 0)
+		logTable.ScrollToBeginning()
+	}
+}
 
-	for _, log := range allLogs {
-		if strings.Contains(strings.ToLower(log.Message), searchLower) ||
-			strings.Contains(strings.ToLower(log.Timestamp), searchLower) ||
-			strings.Contains(strings.ToLower(log.Level), searchLower) ||
-			strings.Contains(strings.ToLower(log.OriginalMessage), searchLower) {
+func clearFilter() {
+	view.Do(func(s *viewer.State) {
+		s.Filters.Text = ""
+		s.Filters.Matcher = nil
+	})
+	filterError = ""
 
-			row := logTable.GetRowCount()
+	rebuildTable()
+}
 
-			idxCell := tview.NewTableCell(log.Index)
-			timeCell := tview.NewTableCell("")
-			levelCell := tview.NewTableCell("")
-			msgCell := tview.NewTableCell("")
+// rebuildTable recomputes the rows of logTable from the entries that pass the active filters
+func rebuildTable() int {
+	var matched int
+	view.Do(func(s *viewer.State) {
+		matched = s.Rebuild()
+	})
 
-			highlightSearchText(timeCell, log.Timestamp, searchText)
-			highlightSearchText(levelCell, log.Level, searchText)
-			highlightSearchText(msgCell, log.Message, searchText)
+	updateStatusBar()
+	updateExprStatus()
 
-			levelCell.SetTextColor(log.LevelColor).SetAlign(tview.AlignCenter)
+	return matched
+}
 
-			logTable.SetCell(row, 0, idxCell)
-			logTable.SetCell(row, 1, timeCell)
-			logTable.SetCell(row, 2, levelCell)
-			logTable.SetCell(row, 3, msgCell)
+type highlightSpan struct {
+	start, end int
+	style      string
+}
 
-			setRowStyle(row, idxCell, timeCell, levelCell, msgCell)
+// highlightSpans collects search, display filter and enabled match expression
+// matches, each in its own style. On overlap the earlier span wins, and at the
+// same offset search wins over the display filter and match expressions.
+func highlightSpans(s *viewer.State, text string) []highlightSpan {
+	var spans []highlightSpan
 
-			displayedLogs++
+	if s.Search != nil {
+		for _, span := range s.Search.Spans(text) {
+			spans = append(spans, highlightSpan{span[0], span[1], "black:aqua"})
 		}
 	}
 
-	updateStatusBar(displayedLogs, searchText)
-
-	if displayedLogs > 0 {
-		logTable.Select(1, 0)
-		logTable.ScrollToBeginning()
+	if s.Filters.Matcher != nil {
+		for _, span := range s.Filters.Matcher.Spans(text) {
+			spans = append(spans, highlightSpan{span[0], span[1], "yellow"})
+		}
 	}
-}
 
-func clearFilter() {
-	currentFilter = ""
-	for i := logTable.GetRowCount() - 1; i > 0; i-- {
-		logTable.RemoveRow(i)
+	for _, expr := range s.Filters.Expressions.Enabled() {
+		color := colorTag(expr.Color)
+		for _, span := range expr.Matcher.Spans(text) {
+			spans = append(spans, highlightSpan{span[0], span[1], color})
+		}
 	}
 
-	for i, log := range allLogs {
-		row := logTable.GetRowCount()
-
-		idxCell := tview.NewTableCell(fmt.Sprintf("%d", i))
-		timeCell := tview.NewTableCell(log.Timestamp)
-		levelCell := tview.NewTableCell(log.Level).
-			SetTextColor(log.LevelColor).
-			SetAlign(tview.AlignCenter)
-		msgCell := tview.NewTableCell(log.Message)
-
-		logTable.SetCell(row, 0, idxCell)
-		logTable.SetCell(row, 1, timeCell)
-		logTable.SetCell(row, 2, levelCell)
-		logTable.SetCell(row, 3, msgCell)
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
 
-		setRowStyle(row, idxCell, timeCell, levelCell, msgCell)
+	result := spans[:0]
+	end := 0
+	for _, span := range spans {
+		if span.start < end {
+			continue
+		}
+		result = append(result, span)
+		end = span.end
 	}
 
-	updateStatusBar(len(allLogs), "")
+	return result
 }
 
-func highlightSearchText(cell *tview.TableCell, text string, searchText string) {
-	if searchText == "" || text == "" {
-		cell.SetText(text)
+// highlightSearchText highlights every match span in text.
+// Spans are byte offsets into the original text, so multi-byte runes and
+// case folding that changes byte lengths are handled correctly.
+func highlightSearchText(s *viewer.State, cell *tview.TableCell, text string) {
+	if text == "" {
+		cell.SetText("")
 		return
 	}
 
-	searchLower := strings.ToLower(searchText)
-	textLower := strings.ToLower(text)
-
 	var result strings.Builder
 	lastIndex := 0
 
-	for {
-		idx := strings.Index(textLower[lastIndex:], searchLower)
-		if idx == -1 {
-			break
-		}
-
-		actualIndex := lastIndex + idx
-
-		result.WriteString(text[lastIndex:actualIndex])
+	for _, span := range highlightSpans(s, text) {
+		result.WriteString(tview.Escape(text[lastIndex:span.start]))
 
-		result.WriteString("[yellow]")
-		result.WriteString(text[actualIndex : actualIndex+len(searchText)])
-		result.WriteString("[white]")
+		result.WriteString("[" + span.style + "]")
+		result.WriteString(tview.Escape(text[span.start:span.end]))
+		result.WriteString("[-:-]")
 
-		lastIndex = actualIndex + len(searchText)
+		lastIndex = span.end
 	}
 
-	result.WriteString(text[lastIndex:])
+	result.WriteString(tview.Escape(text[lastIndex:]))
 
 	cell.SetText(result.String())
 }
 
-func updateStatusBar(displayed int, filter string) {
+func colorTag(color tcell.Color) string {
+	return fmt.Sprintf("#%06x", color.Hex())
+}
+
+func updateStatusBar() {
 	firstLine := statusBar.GetItem(0).(*tview.Flex)
 	infoText := firstLine.GetItem(0).(*tview.TextView)
 
-	if filter == "typing..." {
+	var filtered bool
+	var displayed, retained, dropped, spilled int
+	view.Do(func(s *viewer.State) {
+		filtered = s.Filters.Text != ""
+		displayed = s.Matched
+		retained = s.Store.Retained()
+		dropped = s.Store.Dropped()
+		if spill := s.Store.Spill(); spill != nil {
+			spilled = spill.Len()
+		}
+	})
+
+	var text string
+	if isSearching {
 		if inputFile != "" {
-			infoText.SetText(fmt.Sprintf(
+			text = fmt.Sprintf(
 				"gofly-cli v.%s    Mode: Typing...    %s    Logs: %d",
-				appVersion, currentMode, len(allLogs)))
+				appVersion, currentMode, retained)
 		} else {
-			infoText.SetText(fmt.Sprintf(
+			text = fmt.Sprintf(
 				"gofly-cli v.%s    Mode: Typing...    [%s]    Logs: %d",
-				appVersion, serverAddr, len(allLogs)))
+				appVersion, serverAddr, retained)
 		}
-	} else if filter != "" {
+	} else if filtered || displayed < retained {
 		if inputFile != "" {
-			infoText.SetText(fmt.Sprintf(
+			text = fmt.Sprintf(
 				"gofly-cli v.%s    Mode: Filtered    %s    Logs: %d/%d",
-				appVersion, currentMode, displayed, len(allLogs)))
+				appVersion, currentMode, displayed, retained)
 		} else {
-			infoText.SetText(fmt.Sprintf(
+			text = fmt.Sprintf(
 				"gofly-cli v.%s    Mode: Filtered    [%s]    Logs: %d/%d",
-				appVersion, serverAddr, displayed, len(allLogs)))
+				appVersion, serverAddr, displayed, retained)
 		}
 	} else {
 		if inputFile != "" {
-			infoText.SetText(fmt.Sprintf(
+			text = fmt.Sprintf(
 				"gofly-cli v.%s    Mode: %s    Logs: %d",
-				appVersion, currentMode, len(allLogs)))
+				appVersion, currentMode, retained)
 		} else {
-			infoText.SetText(fmt.Sprintf(
+			text = fmt.Sprintf(
 				"gofly-cli v.%s    Mode: %s    [%s]    Logs: %d",
-				appVersion, currentMode, serverAddr, len(allLogs)))
+				appVersion, currentMode, serverAddr, retained)
+		}
+	}
+
+	if dropped > 0 {
+		text += fmt.Sprintf("    [gray]%d dropped[-]", dropped)
+		if spilled > 0 {
+			text += fmt.Sprintf("[gray], %d on disk[-]", spilled)
 		}
 	}
+
+	if filterError != "" {
+		text += fmt.Sprintf("    [red]%s[-]", tview.Escape(filterError))
+	}
+
+	infoText.SetText(text)
 }
 
+func updateFilterLabel() {
+	if filterMode == filter.ModeRegex {
+		filterLabel.SetText("Display Filter [green](re)[-]:")
+	} else {
+		filterLabel.SetText("Display Filter:")
+	}
+}
+
+const hotkeysHelp = `Hotkeys:
+
+Esc: Quit/Focus log table
+F1: Help
+F2: Cycle minimum level
+F3: Focus filter input
+F4: Clear filter
+F5: Clear all logs
+F6: Toggle autoscroll
+F7: Toggle regex filter
+F8: Match expressions
+F9: Exclusions
+F10: Cancel loading the file
+1-9: Toggle level visibility
+t: Time range (last 5m, 14:30..14:35, 2025-12-04 14:30..)
+g: Jump to time
+c: Context lines around matches (N or BEFORE,AFTER)
+/: Search without hiding rows, n/N: Next/previous match
+f: Saved filters, S or Ctrl-S in the filter: Save current filter
+Up/Down in the filter: Filter history
+l: Call list, Enter shows the selected call (F4 shows all again)
+L: SIP ladder diagram of the selected call
+v: Show all legs of the selected call's conversation
+O: Search entries dropped from memory (with -spill, or a file with its index)
+s: Call statistics (ASR, ACD, PDD, response codes)
+P: Export SIP messages to PCAP (selected call, displayed rows or all)
+E: Export rows to JSON, JSONL, CSV, HTML or plain lines (selected call, displayed rows or all)
+Enter/d: Detail pane, D: Detail at bottom/side, Tab: Focus detail pane
+`
+
 func showHelp() {
 	var helpText string
 	if inputFile != "" {
-		helpText = fmt.Sprintf("Hotkeys:\n\nEsc: Quit/Focus log table\nF1: Help\nF3: Focus filter input\nF4: Clear filter\nF5: Clear all logs\n\nCurrent mode: File [%s]\nSearch works in: Time, Level, Message columns", inputFile)
+		helpText = hotkeysHelp + fmt.Sprintf("\nCurrent mode: %s\nSearch works in: Time, Level, Message columns\nRegex syntax: re:<pattern> or /<pattern>/", currentMode)
 	} else {
-		helpText = fmt.Sprintf("Hotkeys:\n\nEsc: Quit/Focus log table\nF1: Help\nF3: Focus filter input\nF4: Clear filter\nF5: Clear all logs\n\nCurrent mode: Online [%s]\nSearch works in: Time, Level, Message columns\nSUB: Send SUB every 5 sec for updating udp session ttl", serverAddr)
+		helpText = hotkeysHelp + fmt.Sprintf("\nCurrent mode: Online [%s]\nSearch works in: Time, Level, Message columns\nRegex syntax: re:<pattern> or /<pattern>/\nSUB: Send SUB every 5 sec for updating udp session ttl", serverAddr)
 	}
 
 	modal := tview.NewModal().
 		SetText(helpText).
 		AddButtons([]string{"Close"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			app.SetRoot(flex, true).SetFocus(logTable)
+			closeDialog()
 		})
-	app.SetRoot(modal, true).SetFocus(modal)
+	showDialog(modal, modal)
 }
 
-func extractCallID(message string) string {
-	lowerMsg := strings.ToLower(message)
-	if idx := strings.Index(lowerMsg, "call-id:"); idx != -1 {
-		start := idx + len("call-id: ")
-		substr := message[start:]
-
-		end := len(substr)
-		for i := 0; i < len(substr); i++ {
-			if substr[i] == ' ' {
-				end = i
-				break
-			}
-		}
+func showDialog(root tview.Primitive, focus tview.Primitive) {
+	mainActive = false
+	app.SetRoot(root, true).SetFocus(focus)
+}
 
-		callID := strings.TrimSpace(substr[:end])
-		if callID != "" {
-			return callID
+// showPrompt asks for a single line of text. The dialog stays open
+// and shows the error while done fails, done may open another dialog.
+func showPrompt(title, label, initial string, done func(text string) error) {
+	field := tview.NewInputField().
+		SetLabel(label).
+		SetText(initial)
+	errText := tview.NewTextView().SetDynamicColors(true)
+
+	field.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			if err := done(field.GetText()); err != nil {
+				errText.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+				return
+			}
+			if app.GetFocus() == field {
+				closeDialog()
+			}
+		case tcell.KeyEsc:
+			closeDialog()
 		}
-	}
-
-	return ""
-}
+	})
 
-func getSessionColor(callID string) tcell.Color {
-	if callID == "" {
-		return tcell.ColorGray
-	}
+	box := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(field, 1, 0, true).
+		AddItem(errText, 1, 0, false)
+	box.SetBorder(true).SetTitle(title)
 
-	if color, exists := sessionColors[callID]; exists {
-		return color
-	}
+	layout := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(box, 4, 0, true).
+			AddItem(nil, 0, 1, false), 80, 0, true).
+		AddItem(nil, 0, 1, false)
 
-	colorPool := []tcell.Color{
-		tcell.NewHexColor(0xFF6B6B), // Красный
-		tcell.NewHexColor(0xFFD166), // Желтый
-		tcell.NewHexColor(0x06D6A0), // Зеленый
-		tcell.NewHexColor(0x118AB2), // Синий
-		tcell.NewHexColor(0x9D4EDD), // Фиолетовый
-		tcell.NewHexColor(0x00BBF9), // Голубой
-		tcell.NewHexColor(0xFF9E6D), // Оранжевый
-	}
-
-	color := colorPool[colorIndex%len(colorPool)]
-	colorIndex++
+	showDialog(layout, field)
+}
 
-	sessionColors[callID] = color
+// showMessage shows a short notice until it is confirmed
+func showMessage(text string) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			closeDialog()
+		})
+	showDialog(modal, modal)
+}
 
-	return color
+func closeDialog() {
+	mainActive = true
+	app.SetRoot(flex, true).SetFocus(logTable)
 }
 
-func getSessionRowColor(callID string, row int) tcell.Color {
-	sessionColor := getSessionColor(callID)
+func getSessionRowColor(s *viewer.State, callID string, row int) tcell.Color {
+	sessionColor := s.SessionColor(callID)
 
 	r, g, b := sessionColor.RGB()
 
@@ -783,17 +835,27 @@ func getSessionRowColor(callID string, row int) tcell.Color {
 	return tcell.NewRGBColor(int32(mixedR), int32(mixedG), int32(mixedB))
 }
 
-func setRowStyle(row int, cells ...*tview.TableCell) {
-	msgCell := cells[3]
-	callID := extractCallID(msgCell.Text)
+func setRowStyle(s *viewer.State, row int, callID string, cells ...*tview.TableCell) {
+	bgColor, textColor := rowColors(s, row, callID)
+
+	for _, cell := range cells {
+		cell.SetBackgroundColor(bgColor)
+		if cell != cells[2] {
+			cell.SetTextColor(textColor)
+		}
+	}
+}
 
+// rowColors returns the background and text colors of a row, tinted with
+// the session color of the call
+func rowColors(s *viewer.State, row int, callID string) (tcell.Color, tcell.Color) {
 	var bgColor tcell.Color
 	var textColor tcell.Color
 
 	if callID != "" {
-		bgColor = getSessionRowColor(callID, row)
+		bgColor = getSessionRowColor(s, callID, row)
 
-		sessionColor := getSessionColor(callID)
+		sessionColor := s.SessionColor(callID)
 		r, g, b := sessionColor.RGB()
 
 		lightR := int(float64(r) * 1.5)
@@ -829,36 +891,33 @@ func setRowStyle(row int, cells ...*tview.TableCell) {
 		textColor = tcell.ColorWhite
 	}
 
-	for _, cell := range cells {
-		cell.SetBackgroundColor(bgColor)
-		if cell != cells[2] {
-			cell.SetTextColor(textColor)
-		}
-	}
+	return bgColor, textColor
 }
 
-func updateHotBar(hotBar *tview.TextView, autoScrollEnabled bool) {
+func updateHotBar(hotBar *tview.TextView) {
 	hotBar.SetDynamicColors(true)
 
-	f6Color := "[red]"
-	if autoScrollEnabled {
-		f6Color = "[green]"
-	}
-
 	text := fmt.Sprintf(
 		"[yellow]Esc\\Q[-] Quit   "+
 			"[green]F1[-] Help   "+
+			"[green]F2[-] Level   "+
 			"[green]F3[-] Focus Filter   "+
 			"[green]F4[-] Clear Filter   "+
 			"[green]F5[-] Clear   "+
-			"%sF6[-] AutoScroll",
-		f6Color,
+			"%sF6[-] AutoScroll   "+
+			"%sF7[-] Regex   "+
+			"[green]F8[-] Expressions   "+
+			"[green]F9[-] Exclusions",
+		toggleColor(autoScroll),
+		toggleColor(filterMode == filter.ModeRegex),
 	)
 
 	hotBar.SetText(text)
 }
 
-func logWithTime(level, msg string) string {
-	ts := time.Now().Format("2006-01-02 15:04:05")
-	return fmt.Sprintf("[%s] [%s] %s", ts, level, msg)
+func toggleColor(enabled bool) string {
+	if enabled {
+		return "[green]"
+	}
+	return "[red]"
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/pcap"
+	"gofly-cli/internal/viewer"
+	"os"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// showPcapExport asks what to export: the call of the selected row,
+// the rows currently displayed in logTable or all entries
+func showPcapExport() {
+	row, _ := logTable.GetSelection()
+	log, hasEntry := rowEntry(row)
+
+	buttons := []string{"Displayed rows", "All", "Cancel"}
+	if hasEntry && log.CallID != "" {
+		buttons = append([]string{"Selected call"}, buttons...)
+	}
+
+	modal := tview.NewModal().
+		SetText("Export SIP messages to PCAP").
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			switch buttonLabel {
+			case "Selected call":
+				showPcapPrompt(callEntries(log.CallID), "gofly-"+safeFileName(log.CallID)+".pcap")
+			case "Displayed rows":
+				showPcapPrompt(displayedPositions(), "gofly-filtered.pcap")
+			case "All":
+				var all []int
+				view.Do(func(s *viewer.State) {
+					for pos := s.Store.First(); pos < s.Store.Len(); pos++ {
+						all = append(all, pos)
+					}
+				})
+				showPcapPrompt(all, "gofly.pcap")
+			default:
+				closeDialog()
+			}
+		})
+	showDialog(modal, modal)
+}
+
+func showPcapPrompt(positions []int, initial string) {
+	showPrompt(" Export PCAP ", "File: ", initial, func(path string) error {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return fmt.Errorf("empty file name")
+		}
+
+		n, err := writePcap(path, positions)
+		if err != nil {
+			return err
+		}
+		showMessage(fmt.Sprintf("Wrote %d SIP packets to %s", n, path))
+		return nil
+	})
+}
+
+func writePcap(path string, positions []int) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := pcap.WriteSIP(file, view, positions)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// displayedPositions returns the store positions of the rows in logTable
+func displayedPositions() []int {
+	var positions []int
+	view.Do(func(s *viewer.State) {
+		for _, r := range s.Rows {
+			if r.Kind != viewer.SeparatorRow {
+				positions = append(positions, r.Pos)
+			}
+		}
+	})
+	return positions
+}
+
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
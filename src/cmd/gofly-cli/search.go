@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/viewer"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+func setSearch(text string) {
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		view.Do(func(s *viewer.State) {
+			s.Search = nil
+		})
+		rebuildTable()
+		updateSearchStatus(0)
+		return
+	}
+
+	matcher, err := filter.Compile(text, filterMode)
+	if err != nil {
+		searchStatus.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+		return
+	}
+
+	view.Do(func(s *viewer.State) {
+		s.Search = matcher
+	})
+	rebuildTable()
+
+	// start from the row above the selection, so a match on it is found first
+	row, _ := logTable.GetSelection()
+	logTable.Select(row-1, 0)
+	searchNext(true)
+}
+
+// searchNext moves the selection to the next or previous search hit, wrapping around
+func searchNext(forward bool) {
+	row, _ := logTable.GetSelection()
+
+	searching := false
+	target, current := -1, 0
+	view.Do(func(s *viewer.State) {
+		searching = s.Search != nil
+		hits := s.SearchHits
+		if !searching || len(hits) == 0 {
+			return
+		}
+
+		// hits are indexes of rows, logTable rows start below the header
+		var i int
+		if forward {
+			i = sort.SearchInts(hits, row)
+			if i == len(hits) {
+				i = 0
+			}
+		} else {
+			i = sort.SearchInts(hits, row-1) - 1
+			if i < 0 {
+				i = len(hits) - 1
+			}
+		}
+		target, current = hits[i]+1, i+1
+	})
+
+	if !searching {
+		return
+	}
+	if target != -1 {
+		logTable.Select(target, 0)
+	}
+	updateSearchStatus(current)
+}
+
+func updateSearchStatus(current int) {
+	view.Do(func(s *viewer.State) {
+		switch {
+		case s.Search == nil:
+			searchStatus.SetText("")
+		case len(s.SearchHits) == 0:
+			searchStatus.SetText(" [red]no matches[-]")
+		default:
+			searchStatus.SetText(fmt.Sprintf(" match [aqua]%d[-] of [aqua]%d[-]", current, len(s.SearchHits)))
+		}
+	})
+}
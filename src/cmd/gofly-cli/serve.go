@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/server"
+	"os"
+	"time"
+)
+
+// maxReplayGap caps the pause between two lines, logs may be quiet for hours
+const maxReplayGap = 2 * time.Second
+
+// serveFiles replays the lines of the files to the subscribers of a server
+// on opts.ip and opts.port
+func serveFiles(opts *options, files []string) error {
+	for _, path := range files {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+	}
+
+	srv, err := server.Listen(fmt.Sprintf("%s:%s", opts.ip, opts.port))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := runContext(opts.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Run(ctx)
+	}()
+
+	if opts.wait {
+		fmt.Fprintf(os.Stderr, "Listening on %s, waiting for a subscriber\n", srv.Addr())
+		select {
+		case <-srv.Subscribed():
+		case <-ctx.Done():
+		}
+	}
+
+	for ctx.Err() == nil {
+		for _, path := range files {
+			fmt.Fprintf(os.Stderr, "Replaying %s to %d clients on %s\n", path, srv.Clients(), srv.Addr())
+			if err := replay(ctx, srv, path, opts.speed); err != nil {
+				cancel()
+				<-done
+				return err
+			}
+		}
+		if !opts.loop {
+			break
+		}
+	}
+
+	cancel()
+	return <-done
+}
+
+// replay sends the lines of the file, pausing between them as long as their
+// timestamps are apart divided by speed
+func replay(ctx context.Context, srv *server.Server, path string, speed float64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	lines.Buffer(make([]byte, 0, 64<<10), 16<<20)
+
+	var last time.Time
+	for lines.Scan() && ctx.Err() == nil {
+		line := lines.Text()
+
+		if speed > 0 {
+			t := parser.ParseLogLine(line, 0).Time
+			if !t.IsZero() {
+				if !last.IsZero() && t.After(last) {
+					gap := min(time.Duration(float64(t.Sub(last))/speed), maxReplayGap)
+					select {
+					case <-time.After(gap):
+					case <-ctx.Done():
+					}
+				}
+				last = t
+			}
+		}
+
+		srv.Send(line)
+	}
+	return lines.Err()
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/loader"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/store"
+	"gofly-cli/internal/viewer"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showStats opens the call statistics page. It is recomputed every second
+// while new entries arrive, e exports the current numbers as JSON
+func showStats() {
+	page := tview.NewTextView().SetDynamicColors(true)
+	page.SetBorder(true).SetTitle(" Call Statistics ")
+
+	hint := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[yellow]e[-] Export JSON   [yellow]Esc[-] Close")
+
+	var stats *calls.Stats
+	computed := -1
+
+	refresh := func() {
+		view.Do(func(s *viewer.State) {
+			if computed == s.Added {
+				return
+			}
+			computed = s.Added
+			stats = calls.ComputeStats(s.Store, s.Calls)
+			page.SetText(formatStats(stats, true))
+		})
+	}
+
+	done := make(chan struct{})
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			close(done)
+			closeDialog()
+			return nil
+		}
+
+		if event.Rune() == 'e' {
+			close(done)
+			showPrompt(" Export Statistics ", "File: ", "gofly-stats.json", func(path string) error {
+				path = strings.TrimSpace(path)
+				if path == "" {
+					return fmt.Errorf("empty file name")
+				}
+				return stats.WriteJSON(path)
+			})
+			return nil
+		}
+		return event
+	})
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				app.QueueUpdateDraw(refresh)
+			}
+		}
+	}()
+
+	refresh()
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(page, 0, 1, true).
+		AddItem(hint, 1, 0, false)
+
+	showDialog(layout, page)
+}
+
+// formatStats lays the statistics out for the statistics page, or as plain
+// text without color tags
+func formatStats(s *calls.Stats, tags bool) string {
+	var text strings.Builder
+
+	color := func(name string) string {
+		if !tags {
+			return ""
+		}
+		return "[" + name + "]"
+	}
+	reset, escape := color("-"), tview.Escape
+	if !tags {
+		escape = func(text string) string { return text }
+	}
+
+	line := func(name, format string, args ...any) {
+		fmt.Fprintf(&text, "%s%-23s%s %s\n", color("yellow"), name+":", reset, fmt.Sprintf(format, args...))
+	}
+
+	line("Call attempts", "%d", s.Attempts)
+	line("Answered", "%d", s.Answered)
+	line("Failed", "%d", s.Failed)
+	line("Without final response", "%d", s.Pending)
+	line("ASR", "%.1f%%", s.ASR)
+	line("ACD", "%s", secondsText(s.ACD))
+	line("PDD", "p50 %s   p90 %s   p95 %s   p99 %s   max %s",
+		secondsText(s.PDD.P50), secondsText(s.PDD.P90), secondsText(s.PDD.P95),
+		secondsText(s.PDD.P99), secondsText(s.PDD.Max))
+
+	fmt.Fprintf(&text, "\n%sFinal responses%s\n", color("green"), reset)
+	if len(s.FinalCodes) == 0 {
+		fmt.Fprintf(&text, "%snone%s\n", color("gray"), reset)
+	}
+	for _, code := range s.FinalCodes {
+		fmt.Fprintf(&text, "  %s%-28s%s %6d  %5.1f%%\n",
+			color(colorTag(finalColor(code.Code))),
+			escape(strings.TrimSpace(fmt.Sprintf("%d %s", code.Code, code.Reason))), reset,
+			code.Count, float64(code.Count)*100/float64(s.Attempts))
+	}
+
+	fmt.Fprintf(&text, "\n%sTop failing destinations%s\n", color("green"), reset)
+	if len(s.TopFailing) == 0 {
+		fmt.Fprintf(&text, "%snone%s\n", color("gray"), reset)
+	}
+	for _, dest := range s.TopFailing {
+		fmt.Fprintf(&text, "  %-28s %6d of %d attempts\n",
+			escape(dest.Destination), dest.Failures, dest.Attempts)
+	}
+
+	return text.String()
+}
+
+func secondsText(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond).String()
+}
+
+// printStats computes the statistics of the calls in the files, or streamed
+// by the server until interrupted, and prints them
+func printStats(opts *options, files []string) error {
+	budget, err := store.ParseSize(opts.maxMemory)
+	if err != nil {
+		return fmt.Errorf("--max-memory: %w", err)
+	}
+	// an existing index spares parsing, a read-only command doesn't write one
+	loader.UseIndex = !opts.noIndex
+	loader.BuildIndex = false
+	inputs, err := grepInputs(opts, files)
+	if err != nil {
+		return err
+	}
+
+	// calls of dropped entries are not counted, the text is never searched
+	counted, err := viewer.New(viewer.Options{
+		MaxEntries: opts.maxEntries,
+		MaxBytes:   budget,
+		NoText:     true,
+	})
+	if err != nil {
+		return err
+	}
+	defer counted.Close()
+
+	ctx, cancel := runContext(opts.timeout)
+	defer cancel()
+
+	var errs []error
+	for _, in := range inputs {
+		r, err := filter.ParseTimeRange(timeRangeSpec(opts.from, opts.to, opts.last), in.ref)
+		if err != nil {
+			return err
+		}
+
+		err = in.src.Run(ctx, func(entries []model.LogEntry) {
+			kept := make([]model.LogEntry, 0, len(entries))
+			for _, e := range entries {
+				if r.Contains(e.Time) {
+					kept = append(kept, e)
+				}
+			}
+			counted.Add(kept)
+		}, printStatus)
+		if err != nil && ctx.Err() == nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	var stats *calls.Stats
+	counted.Do(func(s *viewer.State) {
+		stats = calls.ComputeStats(s.Store, s.Calls)
+	})
+
+	w, closeOutput, err := createOutput(opts.output)
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		err = stats.EncodeJSON(w)
+	} else {
+		_, err = io.WriteString(w, formatStats(stats, false))
+	}
+	if closeErr := closeOutput(); err == nil {
+		err = closeErr
+	}
+	return err
+}
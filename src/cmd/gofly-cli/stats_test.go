@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatsRetention(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	log := filepath.Join("..", "..", "internal", "calls", "testdata", "stats.log")
+
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		attempts int
+	}{
+		{"all entries", nil, nil, 4},
+		{"max entries", []string{"--max-entries", "5"}, nil, 2},
+		{"max entries from the environment", nil, map[string]string{"GOFLY_MAX_ENTRIES": "5"}, 2},
+		{"max memory", []string{"--max-memory", "1KB"}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			output := filepath.Join(t.TempDir(), "stats.json")
+			args := append([]string{"stats", "--no-index", "--json", "-o", output}, tt.args...)
+			if code := runCommand(append(args, log)); code != 0 {
+				t.Fatalf("exit code %d", code)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stats struct {
+				Attempts int `json:"attempts"`
+			}
+			if err := json.Unmarshal(data, &stats); err != nil {
+				t.Fatal(err)
+			}
+			if stats.Attempts != tt.attempts {
+				t.Errorf("%d attempts, want %d", stats.Attempts, tt.attempts)
+			}
+		})
+	}
+}
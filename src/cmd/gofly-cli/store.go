@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/store"
+	"gofly-cli/internal/viewer"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// spillSearchLimit bounds the dropped entries shown for one search
+const spillSearchLimit = 10000
+
+func showSpillSearchPrompt() {
+	var spill *store.Spill
+	var initial string
+	view.Do(func(s *viewer.State) {
+		spill = s.Store.Spill()
+		initial = s.Filters.Text
+	})
+	if spill == nil && fileIndex == nil {
+		showMessage("Dropped entries are not kept, start gofly-cli with -spill to search them")
+		return
+	}
+
+	showPrompt(" Search Dropped Entries ", "Filter: ", initial, func(text string) error {
+		matcher, err := filter.Compile(strings.TrimSpace(text), filterMode)
+		if err != nil {
+			return err
+		}
+
+		// the search runs without blocking the UI, dropped entries of a
+		// loaded file are still in the file, the store appends other
+		// evicted entries to the spill file
+		idx, path := fileIndex, indexPath
+		go func() {
+			var found []model.LogEntry
+			var err error
+			if idx != nil {
+				found, err = searchFileIndex(idx, path, matcher, spillSearchLimit)
+			} else {
+				found, err = spill.Search(matcher.MatchEntry, spillSearchLimit)
+			}
+
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					showMessage(fmt.Sprintf("Searching dropped entries failed: %v", err))
+					return
+				}
+				showSpillResults(matcher.Expr, found)
+			})
+		}()
+
+		showMessage(fmt.Sprintf("Searching dropped entries for %s...", matcher.Expr))
+		return nil
+	})
+}
+
+// searchFileIndex reads the dropped lines of the file at path that may match
+// according to its index idx, all of them for regex expressions
+func searchFileIndex(idx *index.Index, path string, matcher *filter.Matcher, limit int) ([]model.LogEntry, error) {
+	var dropped int
+	view.Do(func(s *viewer.State) {
+		dropped = min(s.Store.First(), idx.Lines())
+	})
+
+	var lines []int
+	ok := false
+	if text, isText := matcher.Text(); isText {
+		lines, ok = idx.Search(text)
+	}
+	if !ok {
+		lines = make([]int, dropped)
+		for n := range lines {
+			lines[n] = n
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result []model.LogEntry
+	for _, n := range lines {
+		if n >= dropped || len(result) >= limit {
+			break
+		}
+		e, err := idx.Entry(file, n)
+		if err != nil {
+			return result, err
+		}
+		e.Index = strconv.Itoa(n)
+		e.Source = path
+		if matcher.MatchEntry(e) {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+// showSpillResults lists dropped entries, Enter shows the details of one
+func showSpillResults(expr string, found []model.LogEntry) {
+	table := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Dropped entries matching %s: %d ", tview.Escape(expr), len(found)))
+
+	for i, h := range []string{"Idx", "Time", "Level", "Message"} {
+		table.SetCell(0, i, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+	for i, log := range found {
+		table.SetCell(i+1, 0, tview.NewTableCell(log.Index))
+		table.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(log.Timestamp)))
+		table.SetCell(i+1, 2, tview.NewTableCell(tview.Escape(log.Level)).SetTextColor(log.LevelColor))
+		table.SetCell(i+1, 3, tview.NewTableCell(tview.Escape(log.Message)).SetExpansion(1))
+	}
+
+	detail := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true)
+	detail.SetBorder(true).SetTitle(" Details ")
+
+	table.SetSelectionChangedFunc(func(row, column int) {
+		if row >= 1 && row <= len(found) {
+			detail.SetText(formatDetail(found[row-1], nil))
+			detail.ScrollToBeginning()
+		}
+	})
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeDialog()
+			return nil
+		}
+		return event
+	})
+	if len(found) > 0 {
+		table.Select(1, 0)
+	}
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(detail, 0, 1, false)
+
+	showDialog(layout, table)
+}
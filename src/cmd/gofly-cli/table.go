@@ -0,0 +1,117 @@
+package main
+
+import (
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/viewer"
+
+	"github.com/rivo/tview"
+)
+
+// maxCachedRows bounds the cells kept between draws
+const maxCachedRows = 2000
+
+// logContent backs logTable with the rows of view. Cells are created when
+// tview draws them and cached until the rows change.
+type logContent struct {
+	tview.TableContentReadOnly
+	header []*tview.TableCell
+	cells  map[int][]*tview.TableCell
+	// State.Version the cells were rendered for
+	version int
+}
+
+var logRows = &logContent{cells: make(map[int][]*tview.TableCell)}
+
+func (c *logContent) GetRowCount() int {
+	rows := 0
+	view.Do(func(s *viewer.State) {
+		rows = len(s.Rows)
+	})
+	return rows + 1
+}
+
+func (c *logContent) GetColumnCount() int {
+	return len(c.header)
+}
+
+func (c *logContent) GetCell(row, column int) *tview.TableCell {
+	if column < 0 || column >= len(c.header) {
+		return nil
+	}
+	if row == 0 {
+		return c.header[column]
+	}
+
+	var cells []*tview.TableCell
+	view.Do(func(s *viewer.State) {
+		if c.version != s.Version || len(c.cells) >= maxCachedRows {
+			c.cells = make(map[int][]*tview.TableCell)
+			c.version = s.Version
+		}
+		if row < 0 || row > len(s.Rows) {
+			return
+		}
+
+		var ok bool
+		if cells, ok = c.cells[row]; ok {
+			return
+		}
+
+		r := s.Rows[row-1]
+		log, _ := s.Store.Get(r.Pos)
+		switch r.Kind {
+		case viewer.ContextRow:
+			cells = contextCells(log)
+		case viewer.SeparatorRow:
+			cells = separatorCells()
+		default:
+			cells = logCells(s, row, log)
+		}
+		c.cells[row] = cells
+	})
+	if cells == nil {
+		return nil
+	}
+
+	return cells[column]
+}
+
+// rowEntry returns the log entry displayed in the given logTable row
+func rowEntry(row int) (model.LogEntry, bool) {
+	var log model.LogEntry
+	ok := false
+	view.Do(func(s *viewer.State) {
+		log, ok = s.Entry(row - 1)
+	})
+	return log, ok
+}
+
+// findRow returns the row of logTable showing the entry at pos or -1
+func findRow(pos int) int {
+	row := -1
+	view.Do(func(s *viewer.State) {
+		if i := s.Find(pos); i >= 0 {
+			row = i + 1
+		}
+	})
+	return row
+}
+
+// logCells renders a matching entry, highlighting current matches
+func logCells(s *viewer.State, row int, log model.LogEntry) []*tview.TableCell {
+	idxCell := tview.NewTableCell(log.Index)
+	timeCell := tview.NewTableCell("")
+	levelCell := tview.NewTableCell("").
+		SetTextColor(log.LevelColor).
+		SetAlign(tview.AlignCenter)
+	msgCell := tview.NewTableCell("")
+
+	highlightSearchText(s, timeCell, log.Timestamp)
+	highlightSearchText(s, levelCell, log.Level)
+	highlightSearchText(s, msgCell, log.Message)
+
+	// add style to row
+	setRowStyle(s, row, log.CallID, idxCell, timeCell, levelCell, msgCell)
+
+	return []*tview.TableCell{idxCell, timeCell, levelCell, msgCell}
+}
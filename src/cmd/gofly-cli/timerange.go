@@ -0,0 +1,113 @@
+package main
+
+import (
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/viewer"
+	"time"
+)
+
+// timeReference is the moment relative ranges like "last 5m" count back from:
+// now when online, the newest entry when reading a file
+func timeReference() time.Time {
+	if inputFile == "" {
+		return time.Now()
+	}
+
+	var latest time.Time
+	view.Do(func(s *viewer.State) {
+		latest = s.Latest()
+	})
+	if latest.IsZero() {
+		return time.Now()
+	}
+	return latest
+}
+
+func setTimeRange(spec string) error {
+	r, err := filter.ParseTimeRange(spec, timeReference())
+	if err != nil {
+		return err
+	}
+
+	view.Do(func(s *viewer.State) {
+		s.Filters.TimeRange = r
+	})
+	rebuildTable()
+	updateLevelStatus()
+
+	return nil
+}
+
+// jumpToTime selects the displayed row whose timestamp is closest to spec
+func jumpToTime(spec string) error {
+	target, err := filter.ParseTime(spec, timeReference())
+	if err != nil {
+		return err
+	}
+
+	bestRow := -1
+	var bestDiff time.Duration
+	view.Do(func(s *viewer.State) {
+		for i := range s.Rows {
+			log, ok := s.Entry(i)
+			if !ok || log.Time.IsZero() {
+				continue
+			}
+
+			diff := log.Time.Sub(target)
+			if diff < 0 {
+				diff = -diff
+			}
+			if bestRow == -1 || diff < bestDiff {
+				bestRow = i + 1
+				bestDiff = diff
+			}
+		}
+	})
+
+	if bestRow != -1 {
+		logTable.Select(bestRow, 0)
+	}
+
+	return nil
+}
+
+func showTimeRangePrompt() {
+	initial := ""
+	view.Do(func(s *viewer.State) {
+		if !s.Filters.TimeRange.IsZero() {
+			initial = s.Filters.TimeRange.String()
+		}
+	})
+
+	showPrompt(" Time Range (empty to reset) ", "Range: ", initial, setTimeRange)
+}
+
+// applyTimeFlags applies -from/-to/-last and -jump once a file is loaded
+func applyTimeFlags(from, to, last, jump string) {
+	if spec := timeRangeSpec(from, to, last); spec != "" {
+		if err := setTimeRange(spec); err != nil {
+			filterError = err.Error()
+			updateStatusBar()
+		}
+	}
+
+	if jump != "" {
+		if err := jumpToTime(jump); err != nil {
+			filterError = err.Error()
+			updateStatusBar()
+		}
+	}
+}
+
+// timeRangeSpec builds the range of --from, --to and --last for
+// filter.ParseTimeRange
+func timeRangeSpec(from, to, last string) string {
+	if last != "" {
+		return "last " + last
+	}
+	if from != "" || to != "" {
+		return from + ".." + to
+	}
+	return ""
+}
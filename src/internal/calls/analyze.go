@@ -0,0 +1,264 @@
+package calls
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/sip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Flag int
+
+const (
+	FlagRetransmission Flag = iota
+	FlagMissingACK
+	FlagLongPDD
+	FlagFailed
+	FlagNoBYE
+	FlagOneWayReInvite
+)
+
+// PDDThreshold is the post-dial delay above which a call is flagged.
+var PDDThreshold = 5 * time.Second
+
+// Badge is the short form of the flag shown in lists.
+func (f Flag) Badge() string {
+	switch f {
+	case FlagRetransmission:
+		return "RETX"
+	case FlagMissingACK:
+		return "NO-ACK"
+	case FlagLongPDD:
+		return "PDD"
+	case FlagFailed:
+		return "FAIL"
+	case FlagNoBYE:
+		return "NO-BYE"
+	case FlagOneWayReInvite:
+		return "1WAY"
+	default:
+		return "?"
+	}
+}
+
+type Anomaly struct {
+	Flag   Flag
+	Detail string
+	// position of the entry in the log that shows the problem
+	Pos int
+}
+
+// Analysis is the result of following the SIP transactions of a call.
+type Analysis struct {
+	Anomalies []Anomaly
+	// time from the initial INVITE to the first ringing or final response
+	PostDialDelay time.Duration
+	Answered      bool
+	InviteTime    time.Time
+	AnswerTime    time.Time
+	EndTime       time.Time
+	FinalCode     int
+	FinalReason   string
+}
+
+// TalkTime is the time between the answer and the BYE.
+func (a *Analysis) TalkTime() time.Duration {
+	if !a.Answered || a.AnswerTime.IsZero() || a.EndTime.IsZero() {
+		return 0
+	}
+	return a.EndTime.Sub(a.AnswerTime)
+}
+
+// Has reports whether the analysis raised the flag.
+func (a *Analysis) Has(flag Flag) bool {
+	for _, anomaly := range a.Anomalies {
+		if anomaly.Flag == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Badges returns distinct flag badges in the order they were raised.
+func (a *Analysis) Badges() []string {
+	var badges []string
+	seen := make(map[Flag]bool)
+	for _, anomaly := range a.Anomalies {
+		if !seen[anomaly.Flag] {
+			seen[anomaly.Flag] = true
+			badges = append(badges, anomaly.Flag.Badge())
+		}
+	}
+	return badges
+}
+
+type inviteTransaction struct {
+	pos       int
+	cseq      int
+	final     int
+	finalPos  int
+	finalTime time.Time
+	acked     bool
+}
+
+// Analyze follows the dialog and transaction state of the SIP messages
+// in the entries at positions of logs and flags anomalies.
+func Analyze(logs model.Entries, positions []int) *Analysis {
+	a := &Analysis{}
+
+	seen := make(map[string]int)
+	invites := make(map[int]*inviteTransaction)
+	var inviteOrder []*inviteTransaction
+	var initial *inviteTransaction
+	var first initialRequest
+	ringing := false
+	byeSeen := false
+
+	for _, pos := range positions {
+		e, ok := logs.Get(pos)
+		if !ok {
+			continue
+		}
+
+		msg, ok := sip.Parse(e.Message)
+		if !ok {
+			continue
+		}
+
+		cseq, method := msg.CSeq()
+		_, branch := msg.TopVia()
+
+		key := msg.StartLine() + "|" + strconv.Itoa(cseq) + "|" + method + "|" + branch
+		seen[key]++
+		if seen[key] == 2 {
+			a.Anomalies = append(a.Anomalies, Anomaly{
+				Flag:   FlagRetransmission,
+				Detail: fmt.Sprintf("%s (CSeq %d %s) retransmitted", msg.StartLine(), cseq, method),
+				Pos:    pos,
+			})
+		}
+		if seen[key] > 1 {
+			continue
+		}
+
+		if msg.IsRequest {
+			restarted := first.request(msg)
+			switch msg.Method {
+			case "INVITE":
+				tx := &inviteTransaction{pos: pos, cseq: cseq}
+				invites[cseq] = tx
+				inviteOrder = append(inviteOrder, tx)
+
+				if restarted && first.Method == "INVITE" {
+					// the post-dial delay counts from the first attempt
+					if initial == nil {
+						a.InviteTime = e.Time
+					}
+					initial = tx
+					a.FinalCode, a.FinalReason = 0, ""
+				} else if a.Answered && oneWaySDP(msg.Body) {
+					a.Anomalies = append(a.Anomalies, Anomaly{
+						Flag:   FlagOneWayReInvite,
+						Detail: fmt.Sprintf("re-INVITE (CSeq %d) puts media on hold or one-way", cseq),
+						Pos:    pos,
+					})
+				}
+			case "ACK":
+				if tx := invites[cseq]; tx != nil {
+					tx.acked = true
+				}
+			case "BYE", "CANCEL":
+				byeSeen = true
+				if a.EndTime.IsZero() {
+					a.EndTime = e.Time
+				}
+			}
+			continue
+		}
+
+		if method != "INVITE" {
+			continue
+		}
+		tx := invites[cseq]
+		if tx == nil {
+			continue
+		}
+
+		challenge := msg.StatusCode == 401 || msg.StatusCode == 407
+		if tx == initial && !ringing && (msg.StatusCode == 180 || msg.StatusCode == 183 || msg.IsFinal() && !challenge) {
+			ringing = true
+			if !a.InviteTime.IsZero() && !e.Time.IsZero() {
+				a.PostDialDelay = e.Time.Sub(a.InviteTime)
+			}
+		}
+
+		if msg.IsFinal() && tx.final == 0 {
+			tx.final = msg.StatusCode
+			tx.finalPos = pos
+			tx.finalTime = e.Time
+		}
+
+		if tx == initial && first.response(msg) {
+			a.FinalCode = msg.StatusCode
+			a.FinalReason = msg.Reason
+			if msg.StatusCode < 300 {
+				a.Answered = true
+				a.AnswerTime = e.Time
+			}
+		}
+	}
+
+	if initial == nil {
+		return a
+	}
+
+	if a.PostDialDelay > PDDThreshold {
+		a.Anomalies = append(a.Anomalies, Anomaly{
+			Flag:   FlagLongPDD,
+			Detail: fmt.Sprintf("post-dial delay %s", a.PostDialDelay),
+			Pos:    initial.pos,
+		})
+	}
+
+	if a.FinalCode >= 400 {
+		a.Anomalies = append(a.Anomalies, Anomaly{
+			Flag:   FlagFailed,
+			Detail: strings.TrimSpace(fmt.Sprintf("call failed with %d %s", a.FinalCode, a.FinalReason)),
+			Pos:    initial.finalPos,
+		})
+	}
+
+	for _, tx := range inviteOrder {
+		if tx.final != 0 && !tx.acked {
+			a.Anomalies = append(a.Anomalies, Anomaly{
+				Flag:   FlagMissingACK,
+				Detail: fmt.Sprintf("no ACK for %d response to INVITE (CSeq %d)", tx.final, tx.cseq),
+				Pos:    tx.finalPos,
+			})
+		}
+	}
+
+	if a.Answered && !byeSeen {
+		a.Anomalies = append(a.Anomalies, Anomaly{
+			Flag:   FlagNoBYE,
+			Detail: "answered call without BYE",
+			Pos:    initial.finalPos,
+		})
+	}
+
+	return a
+}
+
+// oneWaySDP reports whether an SDP body holds or limits media to one direction.
+func oneWaySDP(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		switch line {
+		case "a=sendonly", "a=recvonly", "a=inactive", "c=IN IP4 0.0.0.0":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package calls
+
+import (
+	"bufio"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"os"
+	"testing"
+	"time"
+)
+
+type entrySlice []model.LogEntry
+
+func (s entrySlice) Get(pos int) (model.LogEntry, bool) {
+	if pos < 0 || pos >= len(s) {
+		return model.LogEntry{}, false
+	}
+	return s[pos], true
+}
+
+// loadFixture parses a log from testdata and tracks its calls
+func loadFixture(t *testing.T, name string) (entrySlice, *Tracker) {
+	t.Helper()
+
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var logs entrySlice
+	tracker := NewTracker()
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		e := parser.ParseLogLine(lines.Text(), len(logs))
+		tracker.Add(len(logs), e)
+		logs = append(logs, e)
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return logs, tracker
+}
+
+func TestAnalyzeAuthenticationRetry(t *testing.T) {
+	logs, tracker := loadFixture(t, "auth_retry.log")
+
+	call := tracker.Get("auth-1@10.0.0.1")
+	if call == nil {
+		t.Fatal("call not tracked")
+	}
+	a := call.Analysis(logs)
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"tracker final code", call.FinalCode, 200},
+		{"tracker method", call.Method, "INVITE"},
+		{"final code", a.FinalCode, 200},
+		{"final reason", a.FinalReason, "OK"},
+		{"answered", a.Answered, true},
+		{"post-dial delay", a.PostDialDelay, 3 * time.Second},
+		{"talk time", a.TalkTime(), time.Minute},
+		{"badges", len(a.Badges()), 0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package calls
+
+import (
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/sip"
+	"sort"
+	"time"
+)
+
+// Call aggregates all log entries sharing a Call-ID.
+type Call struct {
+	CallID string
+	First  time.Time
+	Last   time.Time
+	// positions of the entries in the log, ascending
+	Entries      []int
+	HighestLevel string
+	From         string
+	To           string
+	// method of the initial request, usually INVITE
+	Method      string
+	FinalCode   int
+	FinalReason string
+
+	initial  initialRequest
+	analysis *Analysis
+	// number of entries the cached analysis was built from
+	analyzed int
+}
+
+// Analysis analyzes the SIP transactions of the call. The result is cached
+// until entries are added to or dropped from the call.
+func (c *Call) Analysis(logs model.Entries) *Analysis {
+	if c.analysis == nil || c.analyzed != len(c.Entries) {
+		c.analysis = Analyze(logs, c.Entries)
+		c.analyzed = len(c.Entries)
+	}
+	return c.analysis
+}
+
+func (c *Call) Duration() time.Duration {
+	if c.First.IsZero() || c.Last.IsZero() {
+		return 0
+	}
+	return c.Last.Sub(c.First)
+}
+
+// Answered reports whether the initial request got a 2xx final response.
+func (c *Call) Answered() bool {
+	return c.FinalCode >= 200 && c.FinalCode < 300
+}
+
+type Tracker struct {
+	calls map[string]*Call
+	order []*Call
+	links *correlator
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		calls: make(map[string]*Call),
+		links: newCorrelator(),
+	}
+}
+
+// Add accounts the entry at pos of the log to its call. Entries must be added
+// in log order, entries without a Call-ID are ignored.
+func (t *Tracker) Add(pos int, e model.LogEntry) {
+	if e.CallID == "" {
+		return
+	}
+
+	call, ok := t.calls[e.CallID]
+	if !ok {
+		call = &Call{CallID: e.CallID}
+		t.calls[e.CallID] = call
+		t.order = append(t.order, call)
+		t.links.addCall(e.CallID)
+	}
+
+	call.Entries = append(call.Entries, pos)
+
+	if !e.Time.IsZero() {
+		if call.First.IsZero() || e.Time.Before(call.First) {
+			call.First = e.Time
+		}
+		if e.Time.After(call.Last) {
+			call.Last = e.Time
+		}
+	}
+
+	if e.Level != "" && (call.HighestLevel == "" || parser.Severity(e.Level) > parser.Severity(call.HighestLevel)) {
+		call.HighestLevel = e.Level
+	}
+
+	msg, ok := sip.Parse(e.Message)
+	if !ok {
+		return
+	}
+
+	if call.From == "" {
+		call.From = sip.URIUser(msg.Header("From"))
+	}
+	if call.To == "" {
+		call.To = sip.URIUser(msg.Header("To"))
+	}
+
+	t.links.add(call, msg, e, t.calls)
+
+	// the answer of the initial request is final, responses to re-INVITEs don't change it
+	if msg.IsRequest && call.initial.request(msg) || !msg.IsRequest && call.initial.response(msg) {
+		call.Method = call.initial.Method
+		call.FinalCode = call.initial.Code
+		call.FinalReason = call.initial.Reason
+	}
+}
+
+// Drop forgets entries before pos, e.g. when they were evicted from memory.
+// Calls without entries left are removed, their Call-IDs are returned.
+func (t *Tracker) Drop(pos int) []string {
+	var dropped []string
+	kept := t.order[:0]
+	for _, call := range t.order {
+		i := sort.SearchInts(call.Entries, pos)
+		if i == len(call.Entries) {
+			delete(t.calls, call.CallID)
+			dropped = append(dropped, call.CallID)
+			continue
+		}
+		if i > 0 {
+			call.Entries = append([]int(nil), call.Entries[i:]...)
+			call.analysis = nil
+		}
+		kept = append(kept, call)
+	}
+	clear(t.order[len(kept):])
+	t.order = kept
+	t.links.remove(dropped)
+	return dropped
+}
+
+// Calls returns all calls in order of their first entry.
+func (t *Tracker) Calls() []*Call {
+	return t.order
+}
+
+func (t *Tracker) Get(callID string) *Call {
+	return t.calls[callID]
+}
+
+func (t *Tracker) Len() int {
+	return len(t.order)
+}
+
+// Conversation returns the Call-ID of the first leg of the conversation
+// the call belongs to, the call's own id when it has no linked legs.
+func (t *Tracker) Conversation(callID string) string {
+	return t.links.root(callID)
+}
+
+// Legs returns the Call-IDs of all legs of the call's conversation in order
+// they were seen.
+func (t *Tracker) Legs(callID string) []string {
+	legs := t.links.legs[t.links.root(callID)]
+	if len(legs) == 0 {
+		return []string{callID}
+	}
+	return legs
+}
+
+// Relinked returns a number that changes whenever legs join or leave a
+// conversation, so colors derived from the conversations are outdated.
+func (t *Tracker) Relinked() int {
+	return t.links.changes
+}
+
+// Leg returns the number of the call within its conversation, starting at 0.
+func (t *Tracker) Leg(callID string) int {
+	for i, id := range t.Legs(callID) {
+		if id == callID {
+			return i
+		}
+	}
+	return 0
+}
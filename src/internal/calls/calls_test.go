@@ -0,0 +1,33 @@
+package calls
+
+import "testing"
+
+func TestDropRenewsAnalysis(t *testing.T) {
+	logs, tracker := loadFixture(t, "auth_retry.log")
+	call := tracker.Get("auth-1@10.0.0.1")
+	if call == nil {
+		t.Fatal("call not tracked")
+	}
+	before := call.Analysis(logs)
+	if before.InviteTime.IsZero() {
+		t.Fatal("no INVITE analyzed")
+	}
+
+	// as many entries are evicted as are added, the first INVITE is gone
+	tracker.Drop(2)
+	for _, e := range logs[len(logs)-2:] {
+		logs = append(logs, e)
+		tracker.Add(len(logs)-1, e)
+	}
+
+	after := call.Analysis(logs)
+	if after == before {
+		t.Fatal("analysis of the evicted entries reused")
+	}
+	if !after.InviteTime.After(before.InviteTime) {
+		t.Errorf("INVITE time %v, want the retried INVITE after %v", after.InviteTime, before.InviteTime)
+	}
+	if call.Analysis(logs) != after {
+		t.Error("unchanged call analyzed again")
+	}
+}
@@ -0,0 +1,259 @@
+package calls
+
+import (
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/sip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LinkHeaders are headers whose equal values put calls into one conversation,
+// e.g. a leg id the B2BUA copies to every leg it creates.
+var LinkHeaders []string
+
+// LinkWindow is the time within which initial INVITEs with the same From and
+// To numbers are treated as legs of one conversation, 0 disables the rule.
+var LinkWindow = 2 * time.Second
+
+// minNumberMatch is the number of trailing digits two numbers must share,
+// so "+79001234567" and "89001234567" are the same subscriber
+const minNumberMatch = 10
+
+type recentInvite struct {
+	callID string
+	from   string
+	to     string
+	time   time.Time
+}
+
+// correlator groups the legs a B2BUA creates for one conversation. Legs are
+// linked when an X- header of one leg carries the Call-ID of another, when
+// they share the value of a LinkHeaders header or when their initial INVITEs
+// have the same numbers within LinkWindow.
+type correlator struct {
+	parent map[string]string
+	// order in which calls were seen
+	seq map[string]int
+	// legs of a conversation by its first call
+	legs map[string][]string
+	// calls referencing a Call-ID in an X- header that wasn't seen yet
+	refs map[string][]string
+	// the values each call waits for in refs
+	waiting    map[string][]string
+	linkValues map[string]string
+	// the keys of linkValues each call owns
+	linkKeys map[string][]string
+	invites  []recentInvite
+	// next value of seq
+	next int
+	// counts the changes of conversations, which change the leg colors
+	changes int
+}
+
+func newCorrelator() *correlator {
+	return &correlator{
+		parent:     make(map[string]string),
+		seq:        make(map[string]int),
+		legs:       make(map[string][]string),
+		refs:       make(map[string][]string),
+		waiting:    make(map[string][]string),
+		linkValues: make(map[string]string),
+		linkKeys:   make(map[string][]string),
+	}
+}
+
+func (c *correlator) addCall(callID string) {
+	c.parent[callID] = callID
+	c.seq[callID] = c.next
+	c.next++
+	c.legs[callID] = []string{callID}
+
+	for _, other := range c.refs[callID] {
+		c.union(other, callID)
+	}
+	delete(c.refs, callID)
+}
+
+// remove forgets the calls, their conversations keep the other legs
+func (c *correlator) remove(callIDs []string) {
+	if len(callIDs) == 0 {
+		return
+	}
+
+	dropped := make(map[string]bool, len(callIDs))
+	roots := make(map[string]bool)
+	for _, id := range callIDs {
+		dropped[id] = true
+		roots[c.root(id)] = true
+	}
+
+	for root := range roots {
+		var kept []string
+		for _, leg := range c.legs[root] {
+			if !dropped[leg] {
+				kept = append(kept, leg)
+			}
+		}
+		delete(c.legs, root)
+		if len(kept) == 0 {
+			continue
+		}
+		if len(kept) > 1 || kept[0] != root {
+			c.changes++
+		}
+		// the first leg left names the conversation
+		for _, leg := range kept {
+			c.parent[leg] = kept[0]
+		}
+		c.legs[kept[0]] = kept
+	}
+
+	for _, id := range callIDs {
+		delete(c.parent, id)
+		delete(c.seq, id)
+
+		for _, key := range c.linkKeys[id] {
+			if c.linkValues[key] == id {
+				delete(c.linkValues, key)
+			}
+		}
+		delete(c.linkKeys, id)
+
+		for _, value := range c.waiting[id] {
+			refs := slices.DeleteFunc(c.refs[value], func(other string) bool { return other == id })
+			if len(refs) == 0 {
+				delete(c.refs, value)
+			} else {
+				c.refs[value] = refs
+			}
+		}
+		delete(c.waiting, id)
+	}
+
+	c.invites = slices.DeleteFunc(c.invites, func(inv recentInvite) bool { return dropped[inv.callID] })
+}
+
+func (c *correlator) root(callID string) string {
+	for {
+		parent, ok := c.parent[callID]
+		if !ok || parent == callID {
+			return callID
+		}
+		// path halving
+		c.parent[callID] = c.parent[parent]
+		callID = parent
+	}
+}
+
+// union merges the conversations of a and b, the one that started first
+// keeps its id
+func (c *correlator) union(a, b string) {
+	ra, rb := c.root(a), c.root(b)
+	if ra == rb {
+		return
+	}
+	if c.seq[rb] < c.seq[ra] {
+		ra, rb = rb, ra
+	}
+	c.parent[rb] = ra
+	legs := append(c.legs[ra], c.legs[rb]...)
+	sort.Slice(legs, func(i, j int) bool { return c.seq[legs[i]] < c.seq[legs[j]] })
+	c.legs[ra] = legs
+	delete(c.legs, rb)
+	c.changes++
+}
+
+func (c *correlator) add(call *Call, msg *sip.Message, e model.LogEntry, calls map[string]*Call) {
+	for _, h := range msg.Headers {
+		name := strings.ToLower(h.Name)
+		value := strings.TrimSpace(h.Value)
+		if value == "" {
+			continue
+		}
+
+		if isLinkHeader(name) {
+			key := name + "|" + value
+			if other, ok := c.linkValues[key]; ok {
+				c.union(other, call.CallID)
+			} else {
+				c.linkValues[key] = call.CallID
+				c.linkKeys[call.CallID] = append(c.linkKeys[call.CallID], key)
+			}
+		}
+
+		if !strings.HasPrefix(name, "x-") || value == call.CallID {
+			continue
+		}
+		if _, ok := calls[value]; ok {
+			c.union(value, call.CallID)
+		} else if looksLikeCallID(name, value) && !slices.Contains(c.refs[value], call.CallID) {
+			c.refs[value] = append(c.refs[value], call.CallID)
+			c.waiting[call.CallID] = append(c.waiting[call.CallID], value)
+		}
+	}
+
+	if LinkWindow <= 0 || e.Time.IsZero() || !msg.IsRequest || msg.Method != "INVITE" ||
+		sip.Tag(msg.Header("To")) != "" {
+		return
+	}
+
+	from := sip.URIUser(msg.Header("From"))
+	to := sip.URIUser(msg.Header("To"))
+	if from == "" || to == "" {
+		return
+	}
+
+	for i := len(c.invites) - 1; i >= 0; i-- {
+		inv := c.invites[i]
+		if e.Time.Sub(inv.time) > LinkWindow {
+			// invites older than the window are never needed again
+			c.invites = c.invites[i+1:]
+			break
+		}
+		if inv.callID != call.CallID && sameNumber(inv.from, from) && sameNumber(inv.to, to) {
+			c.union(inv.callID, call.CallID)
+		}
+	}
+	c.invites = append(c.invites, recentInvite{callID: call.CallID, from: from, to: to, time: e.Time})
+}
+
+func sameNumber(a, b string) bool {
+	da, db := digits(a), digits(b)
+	if da == "" || db == "" {
+		return a == b
+	}
+	a, b = da, db
+	if len(a) < minNumberMatch || len(b) < minNumberMatch {
+		return a == b
+	}
+	return a[len(a)-minNumberMatch:] == b[len(b)-minNumberMatch:]
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// looksLikeCallID tells X- headers that may name a call not seen yet from
+// the many others, which are not worth remembering
+func looksLikeCallID(name, value string) bool {
+	if len(value) > 256 || strings.ContainsAny(value, " \t<>;,\"") {
+		return false
+	}
+	return strings.Contains(name, "call") || strings.Contains(value, "@") || len(value) >= 16
+}
+
+func isLinkHeader(name string) bool {
+	for _, link := range LinkHeaders {
+		if strings.EqualFold(link, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package calls
+
+import (
+	"gofly-cli/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestLegs(t *testing.T) {
+	LinkHeaders = []string{"X-Leg-ID"}
+	defer func() { LinkHeaders = nil }()
+
+	_, tracker := loadFixture(t, "b2b.log")
+
+	tests := []struct {
+		callID string
+		// entries before drop are evicted first
+		drop int
+		want []string
+	}{
+		{"leg-b1", 0, []string{"leg-a1", "leg-b1"}},
+		{"leg-a2", 0, []string{"leg-a2", "leg-b2"}},
+		{"leg-b3", 0, []string{"leg-a3", "leg-b3"}},
+		{"lonely", 0, []string{"lonely"}},
+		{"leg-b1", 1, []string{"leg-b1"}},
+		{"leg-b2", 3, []string{"leg-b2"}},
+		{"leg-b3", 5, []string{"leg-b3"}},
+	}
+	for _, tt := range tests {
+		if tt.drop > 0 {
+			tracker.Drop(tt.drop)
+		}
+		if got := tracker.Legs(tt.callID); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("after drop %d: Legs(%s) = %v, want %v", tt.drop, tt.callID, got, tt.want)
+		}
+		if got := tracker.Conversation(tt.callID); got != tt.want[0] {
+			t.Errorf("after drop %d: Conversation(%s) = %s, want %s", tt.drop, tt.callID, got, tt.want[0])
+		}
+	}
+}
+
+func TestCorrelatorForgetsDroppedCalls(t *testing.T) {
+	LinkHeaders = []string{"X-Leg-ID"}
+	defer func() { LinkHeaders = nil }()
+
+	invite := func(callID, headers string) model.LogEntry {
+		return model.LogEntry{
+			CallID: callID,
+			Message: `INVITE sip:1@10.0.0.9 SIP/2.0\r\nVia: SIP/2.0/UDP 10.0.0.1;branch=z9\r\nFrom: <sip:2@10.0.0.1>;tag=1\r\n` +
+				`To: <sip:1@10.0.0.9>\r\nCall-ID: ` + callID + `\r\nCSeq: 1 INVITE\r\n` + headers,
+		}
+	}
+
+	tracker := NewTracker()
+	tracker.Add(0, invite("a", `X-Orig-Call-ID: future@10.0.0.1\r\nX-Leg-ID: conv\r\nX-Trace: some trace value\r\n`))
+	tracker.Add(1, invite("b", `X-Leg-ID: conv\r\n`))
+
+	links := tracker.links
+	if len(links.refs) != 1 {
+		t.Errorf("refs = %v, want only the Call-ID", links.refs)
+	}
+
+	tracker.Drop(2)
+	for name, size := range map[string]int{
+		"parent":     len(links.parent),
+		"seq":        len(links.seq),
+		"legs":       len(links.legs),
+		"refs":       len(links.refs),
+		"waiting":    len(links.waiting),
+		"linkValues": len(links.linkValues),
+		"linkKeys":   len(links.linkKeys),
+	} {
+		if size != 0 {
+			t.Errorf("%s keeps %d dropped calls", name, size)
+		}
+	}
+}
+
+func TestRelinked(t *testing.T) {
+	LinkHeaders = []string{"X-Leg-ID"}
+	defer func() { LinkHeaders = nil }()
+
+	entries, _ := loadFixture(t, "b2b.log")
+	tracker := NewTracker()
+
+	changes := 0
+	last := tracker.Relinked()
+	for pos, e := range entries {
+		tracker.Add(pos, e)
+		if tracker.Relinked() != last {
+			last = tracker.Relinked()
+			changes++
+		}
+	}
+	if changes != 3 {
+		t.Errorf("conversations changed %d times, want 3", changes)
+	}
+
+	// the second leg is left alone in the conversation
+	tracker.Drop(1)
+	if tracker.Relinked() == last {
+		t.Error("dropping the first leg of a conversation didn't change it")
+	}
+	last = tracker.Relinked()
+
+	// calls without other legs leave no conversation behind
+	tracker.Drop(len(entries))
+	if tracker.Relinked() != last {
+		t.Error("dropping the last legs changed a conversation")
+	}
+}
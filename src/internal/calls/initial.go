@@ -0,0 +1,51 @@
+package calls
+
+import "gofly-cli/internal/sip"
+
+// initialRequest follows the initial transaction of a call: the first
+// request, or the same request sent again without To-tag after a 401 or
+// 407 asked for credentials. The Tracker and Analyze share it, so the final
+// code of a call and its analysis agree.
+type initialRequest struct {
+	Method string
+	CSeq   int
+	// final response, 0 while there is none
+	Code   int
+	Reason string
+}
+
+// request reports whether the request msg starts the initial transaction
+func (r *initialRequest) request(msg *sip.Message) bool {
+	if msg.Method == "ACK" || msg.Method == "CANCEL" {
+		return false
+	}
+	cseq, _ := msg.CSeq()
+
+	switch {
+	case r.Method == "":
+	case msg.Method == r.Method && r.challenged() && cseq != r.CSeq && sip.Tag(msg.Header("To")) == "":
+	default:
+		return false
+	}
+
+	*r = initialRequest{Method: msg.Method, CSeq: cseq}
+	return true
+}
+
+// response reports whether the response msg is the first final response
+// of the initial transaction
+func (r *initialRequest) response(msg *sip.Message) bool {
+	cseq, method := msg.CSeq()
+	if !msg.IsFinal() || r.Code != 0 || method != r.Method || cseq != r.CSeq {
+		return false
+	}
+
+	r.Code, r.Reason = msg.StatusCode, msg.Reason
+	return true
+}
+
+// challenged reports whether the transaction was answered with a request
+// for authentication
+func (r *initialRequest) challenged() bool {
+	return r.Code == 401 || r.Code == 407
+}
@@ -0,0 +1,187 @@
+package calls
+
+import (
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/sip"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arrow is one SIP message of a ladder diagram.
+type Arrow struct {
+	// position of the entry in the log
+	Pos     int
+	Time    time.Time
+	From    int
+	To      int
+	Label   string
+	Message *sip.Message
+}
+
+// Ladder is a sequence diagram of the SIP messages of a call.
+type Ladder struct {
+	Participants []string
+	Arrows       []Arrow
+}
+
+const defaultSIPPort = "5060"
+
+// BuildLadder extracts SIP messages from the entries at positions of logs.
+// A request goes from its top Via to the Request-URI host, a response goes
+// back from where the matching request went to the top Via.
+func BuildLadder(logs model.Entries, positions []int) *Ladder {
+	ladder := &Ladder{}
+	index := make(map[string]int)
+	// destination of requests by transaction (branch + CSeq)
+	requestDst := make(map[string]string)
+
+	participant := func(addr string) int {
+		if i, ok := index[addr]; ok {
+			return i
+		}
+		index[addr] = len(ladder.Participants)
+		ladder.Participants = append(ladder.Participants, addr)
+		return index[addr]
+	}
+
+	for _, pos := range positions {
+		e, ok := logs.Get(pos)
+		if !ok {
+			continue
+		}
+
+		msg, ok := sip.Parse(e.Message)
+		if !ok {
+			continue
+		}
+
+		via, branch := msg.TopVia()
+		via = normalizeAddr(via)
+		key := transactionKey(msg, branch)
+
+		var src, dst string
+		if msg.IsRequest {
+			src = via
+			dst = normalizeAddr(sip.URIHost(msg.RequestURI))
+			requestDst[key] = dst
+		} else {
+			dst = via
+			src = requestDst[key]
+			if src == "" {
+				src = normalizeAddr(sip.URIHost(msg.Header("To")))
+			}
+		}
+		if src == "" {
+			src = "?"
+		}
+		if dst == "" {
+			dst = "?"
+		}
+
+		label := msg.Method
+		if !msg.IsRequest {
+			label = strings.TrimSpace(strconv.Itoa(msg.StatusCode) + " " + msg.Reason)
+		}
+
+		ladder.Arrows = append(ladder.Arrows, Arrow{
+			Pos:     pos,
+			Time:    e.Time,
+			From:    participant(src),
+			To:      participant(dst),
+			Label:   label,
+			Message: msg,
+		})
+	}
+
+	return ladder
+}
+
+func transactionKey(msg *sip.Message, branch string) string {
+	num, method := msg.CSeq()
+	// ACK to a non-2xx belongs to the INVITE transaction
+	if method == "ACK" {
+		method = "INVITE"
+	}
+	return branch + "|" + strconv.Itoa(num) + "|" + method
+}
+
+// normalizeAddr adds the default SIP port, so "10.0.0.1" and "10.0.0.1:5060" are one participant
+func normalizeAddr(addr string) string {
+	if addr == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), defaultSIPPort)
+}
+
+// Header renders participant names centered over their columns.
+func (l *Ladder) Header(colWidth int) string {
+	line := make([]rune, len(l.Participants)*colWidth)
+	for i := range line {
+		line[i] = ' '
+	}
+
+	for i, name := range l.Participants {
+		runes := []rune(name)
+		if len(runes) > colWidth-1 {
+			runes = runes[:colWidth-1]
+		}
+		start := i*colWidth + (colWidth-len(runes))/2
+		copy(line[start:], runes)
+	}
+
+	return string(line)
+}
+
+// Render draws the arrow between the lifelines of its participants.
+func (l *Ladder) Render(a Arrow, colWidth int) string {
+	line := make([]rune, len(l.Participants)*colWidth)
+	for i := range line {
+		line[i] = ' '
+	}
+	for i := range l.Participants {
+		line[i*colWidth+colWidth/2] = '│'
+	}
+
+	from := a.From*colWidth + colWidth/2
+	to := a.To*colWidth + colWidth/2
+
+	if from == to {
+		// message to itself, draw a loop marker next to the lifeline
+		text := []rune("↺ " + a.Label)
+		if end := from + 1 + len(text); end > len(line) {
+			text = text[:len(line)-from-1]
+		}
+		copy(line[from+1:], text)
+		return string(line)
+	}
+
+	left, right := from, to
+	if left > right {
+		left, right = right, left
+	}
+	for i := left + 1; i < right; i++ {
+		line[i] = '─'
+	}
+	if from < to {
+		line[right-1] = '>'
+	} else {
+		line[left+1] = '<'
+	}
+
+	label := []rune(" " + a.Label + " ")
+	space := right - left - 3
+	if space > 0 {
+		if len(label) > space {
+			label = label[:space]
+		}
+		start := left + 2 + (space-len(label))/2
+		copy(line[start:], label)
+	}
+
+	return string(line)
+}
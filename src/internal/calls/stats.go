@@ -0,0 +1,179 @@
+package calls
+
+import (
+	"encoding/json"
+	"gofly-cli/internal/model"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// TopDestinations is the number of failing destinations kept in Stats.
+const TopDestinations = 10
+
+type CodeCount struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason,omitempty"`
+	Count  int    `json:"count"`
+}
+
+type DestinationCount struct {
+	Destination string `json:"destination"`
+	Attempts    int    `json:"attempts"`
+	Failures    int    `json:"failures"`
+}
+
+// Percentiles of a duration in seconds.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Stats are the health numbers of the INVITE calls in a log.
+type Stats struct {
+	Attempts int `json:"attempts"`
+	Answered int `json:"answered"`
+	Failed   int `json:"failed"`
+	// calls still without a final response
+	Pending int `json:"pending"`
+	// answer-seizure ratio in percent
+	ASR float64 `json:"asr"`
+	// average talk time of answered calls that were hung up, in seconds
+	ACD        float64            `json:"acd_seconds"`
+	PDD        Percentiles        `json:"pdd_seconds"`
+	FinalCodes []CodeCount        `json:"final_codes"`
+	TopFailing []DestinationCount `json:"top_failing_destinations"`
+}
+
+// ComputeStats computes statistics over the INVITE calls of the tracker.
+func ComputeStats(logs model.Entries, tracker *Tracker) *Stats {
+	s := &Stats{FinalCodes: []CodeCount{}, TopFailing: []DestinationCount{}}
+
+	codes := make(map[int]*CodeCount)
+	destinations := make(map[string]*DestinationCount)
+	var pdds []time.Duration
+	var talk time.Duration
+	talkCalls := 0
+
+	for _, call := range tracker.Calls() {
+		if call.Method != "INVITE" {
+			continue
+		}
+		s.Attempts++
+
+		a := call.Analysis(logs)
+		if a.PostDialDelay > 0 {
+			pdds = append(pdds, a.PostDialDelay)
+		}
+
+		dest := destinations[call.To]
+		if dest == nil {
+			dest = &DestinationCount{Destination: call.To}
+			destinations[call.To] = dest
+		}
+		dest.Attempts++
+
+		switch {
+		case a.FinalCode == 0:
+			s.Pending++
+			continue
+		case a.Answered:
+			s.Answered++
+			if t := a.TalkTime(); t > 0 {
+				talk += t
+				talkCalls++
+			}
+		case a.FinalCode >= 300:
+			s.Failed++
+			dest.Failures++
+		}
+
+		code := codes[a.FinalCode]
+		if code == nil {
+			code = &CodeCount{Code: a.FinalCode, Reason: a.FinalReason}
+			codes[a.FinalCode] = code
+		}
+		code.Count++
+	}
+
+	if s.Attempts > 0 {
+		s.ASR = float64(s.Answered) * 100 / float64(s.Attempts)
+	}
+	if talkCalls > 0 {
+		s.ACD = (talk / time.Duration(talkCalls)).Seconds()
+	}
+	s.PDD = percentiles(pdds)
+
+	for _, code := range codes {
+		s.FinalCodes = append(s.FinalCodes, *code)
+	}
+	sort.Slice(s.FinalCodes, func(i, j int) bool {
+		if s.FinalCodes[i].Count != s.FinalCodes[j].Count {
+			return s.FinalCodes[i].Count > s.FinalCodes[j].Count
+		}
+		return s.FinalCodes[i].Code < s.FinalCodes[j].Code
+	})
+
+	for _, dest := range destinations {
+		if dest.Failures > 0 {
+			s.TopFailing = append(s.TopFailing, *dest)
+		}
+	}
+	sort.Slice(s.TopFailing, func(i, j int) bool {
+		if s.TopFailing[i].Failures != s.TopFailing[j].Failures {
+			return s.TopFailing[i].Failures > s.TopFailing[j].Failures
+		}
+		return s.TopFailing[i].Destination < s.TopFailing[j].Destination
+	})
+	if len(s.TopFailing) > TopDestinations {
+		s.TopFailing = s.TopFailing[:TopDestinations]
+	}
+
+	return s
+}
+
+func percentiles(values []time.Duration) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	at := func(p float64) float64 {
+		// nearest-rank percentile
+		idx := int(math.Ceil(p*float64(len(values)))) - 1
+		return values[max(idx, 0)].Seconds()
+	}
+
+	return Percentiles{
+		P50: at(0.50),
+		P90: at(0.90),
+		P95: at(0.95),
+		P99: at(0.99),
+		Max: values[len(values)-1].Seconds(),
+	}
+}
+
+// WriteJSON writes the statistics as indented JSON to path.
+func (s *Stats) WriteJSON(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = s.EncodeJSON(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// EncodeJSON writes the statistics as indented JSON to w.
+func (s *Stats) EncodeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,38 @@
+package calls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	logs, tracker := loadFixture(t, "stats.log")
+	s := ComputeStats(logs, tracker)
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"attempts", s.Attempts, 4},
+		{"answered", s.Answered, 2},
+		{"failed", s.Failed, 1},
+		{"pending", s.Pending, 1},
+		{"asr", s.ASR, 50.0},
+		// 60s and 30s
+		{"acd", s.ACD, 45.0},
+		// 1s, 2s and 3s, the call without response has none
+		{"pdd", s.PDD, Percentiles{P50: 2, P90: 3, P95: 3, P99: 3, Max: 3}},
+		{"final codes", s.FinalCodes, []CodeCount{
+			{Code: 200, Reason: "OK", Count: 2},
+			{Code: 486, Reason: "Busy Here", Count: 1},
+		}},
+		{"top failing", s.TopFailing, []DestinationCount{
+			{Destination: "74950000002", Attempts: 1, Failures: 1},
+		}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appDir = "gofly-cli"
+
+// Dir returns the per-user config directory of gofly-cli. It may not exist,
+// writers create it.
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDir), nil
+}
+
+// CacheDir returns the per-user cache directory of gofly-cli. It may not
+// exist, writers create it.
+func CacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDir), nil
+}
+
+// writeFile writes data to path, creating its directory if needed
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProjectFiltersFile is looked up in the working directory first,
+// so a team can share its filters by committing the file.
+const ProjectFiltersFile = ".gofly-filters.json"
+
+const userFiltersFile = "filters.json"
+
+// SavedFilter is a named display filter. Regex filters are stored
+// with the "re:" prefix, so the file does not depend on the current mode.
+type SavedFilter struct {
+	Name   string `json:"name"`
+	Filter string `json:"filter"`
+}
+
+type Filters struct {
+	Path    string        `json:"-"`
+	Filters []SavedFilter `json:"filters"`
+}
+
+// FiltersPath resolves the saved filters file: an explicit path,
+// the project file if it exists, or the per-user file.
+func FiltersPath(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+
+	if _, err := os.Stat(ProjectFiltersFile); err == nil {
+		return ProjectFiltersFile, nil
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		// without a config directory filters can't be saved, see Save
+		return "", nil
+	}
+	return filepath.Join(dir, userFiltersFile), nil
+}
+
+// LoadFilters reads saved filters from path, a missing file or an empty
+// path gives an empty list.
+func LoadFilters(path string) (*Filters, error) {
+	filters := &Filters{Path: path}
+	if path == "" {
+		return filters, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return filters, nil
+	}
+	if err != nil {
+		return filters, err
+	}
+
+	if err := json.Unmarshal(data, filters); err != nil {
+		return filters, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return filters, nil
+}
+
+func (f *Filters) Save() error {
+	if f.Path == "" {
+		return errors.New("no config directory to save filters in, set $HOME or use --filters")
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(f.Path, append(data, '\n'))
+}
+
+// Set adds a filter or replaces the one with the same name.
+func (f *Filters) Set(name, filter string) {
+	for i := range f.Filters {
+		if f.Filters[i].Name == name {
+			f.Filters[i].Filter = filter
+			return
+		}
+	}
+	f.Filters = append(f.Filters, SavedFilter{Name: name, Filter: filter})
+}
+
+func (f *Filters) Remove(i int) {
+	if i < 0 || i >= len(f.Filters) {
+		return
+	}
+	f.Filters = append(f.Filters[:i], f.Filters[i+1:]...)
+}
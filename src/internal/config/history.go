@@ -0,0 +1,66 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	historyFile = "history"
+	historySize = 200
+)
+
+// LoadHistory returns previously applied filters, oldest first.
+func LoadHistory() []string {
+	dir, err := Dir()
+	if err != nil {
+		return nil
+	}
+
+	file, err := os.Open(filepath.Join(dir, historyFile))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history = append(history, line)
+		}
+	}
+
+	return history
+}
+
+// AddHistory moves text to the end of history, dropping the oldest entries
+// above the size limit, and stores the result.
+func AddHistory(history []string, text string) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text, "\r\n") {
+		return history, nil
+	}
+
+	result := make([]string, 0, len(history)+1)
+	for _, item := range history {
+		if item != text {
+			result = append(result, item)
+		}
+	}
+	result = append(result, text)
+
+	if len(result) > historySize {
+		result = result[len(result)-historySize:]
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return result, err
+	}
+
+	data := strings.Join(result, "\n") + "\n"
+	return result, writeFile(filepath.Join(dir, historyFile), []byte(data))
+}
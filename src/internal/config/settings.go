@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// settingsFiles are looked up in Dir in this order
+var settingsFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Settings are the defaults of command line flags, keyed by flag name:
+//
+//	profile: prod
+//	defaults:
+//	  max-memory: 512MB
+//	  link-header: [X-Leg-ID]
+//	profiles:
+//	  prod:
+//	    ip: 10.0.0.5
+//	    port: 9090
+//
+// A profile is applied over the defaults, usually to name a server.
+type Settings struct {
+	Path string `yaml:"-" toml:"-"`
+	// used when no profile is selected
+	Profile  string                    `yaml:"profile" toml:"profile"`
+	Defaults map[string]any            `yaml:"defaults" toml:"defaults"`
+	Profiles map[string]map[string]any `yaml:"profiles" toml:"profiles"`
+}
+
+// SettingsPath resolves the config file: an explicit path or the first
+// config file found in Dir. It is empty when there is none.
+func SettingsPath(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		// no config directory, no config
+		return "", nil
+	}
+	for _, name := range settingsFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// LoadSettings reads a YAML or TOML config file, chosen by its extension.
+// An empty path gives empty settings.
+func LoadSettings(path string) (*Settings, error) {
+	settings := &Settings{Path: path}
+	if path == "" {
+		return settings, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, settings)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, settings)
+	default:
+		return settings, fmt.Errorf("%s: unknown config format, use .yaml or .toml", path)
+	}
+	if err != nil {
+		return settings, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (s *Settings) ProfileNames() []string {
+	names := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Values returns the flag values of the profile applied over the defaults,
+// an empty profile selects s.Profile. Lists are joined with commas.
+func (s *Settings) Values(profile string) (map[string]string, error) {
+	if profile == "" {
+		profile = s.Profile
+	}
+
+	values := make(map[string]string)
+	for name, v := range s.Defaults {
+		values[name] = flagValue(v)
+	}
+
+	if profile == "" {
+		return values, nil
+	}
+	p, ok := s.Profiles[profile]
+	if !ok {
+		return values, errors.New("unknown profile " + profile)
+	}
+	for name, v := range p {
+		values[name] = flagValue(v)
+	}
+	return values, nil
+}
+
+func flagValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = flagValue(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,369 @@
+// Package export writes log entries as text, JSON lines, a JSON array, CSV,
+// HTML or PCAP for scripts, other tools and tickets.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/pcap"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type Format int
+
+const (
+	// Text is the original log line, colored when Options.Color is set
+	Text Format = iota
+	// Plain is the original log line without colors
+	Plain
+	JSONL
+	CSV
+	// PCAP holds the SIP messages of the entries, see pcap.WriteSIP
+	PCAP
+	// HTML is a standalone page with the rows colored like in the viewer
+	HTML
+	// JSON is one array of the records written as JSONL
+	JSON
+)
+
+var formatNames = []string{"text", "plain", "jsonl", "csv", "pcap", "html", "json"}
+
+var formatExtensions = map[string]Format{
+	".log":   Plain,
+	".txt":   Plain,
+	".jsonl": JSONL,
+	".json":  JSON,
+	".csv":   CSV,
+	".pcap":  PCAP,
+	".html":  HTML,
+	".htm":   HTML,
+}
+
+// Names returns the names of the formats.
+func Names() []string {
+	return formatNames
+}
+
+func (f Format) String() string {
+	if int(f) < len(formatNames) {
+		return formatNames[f]
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+func ParseFormat(name string) (Format, error) {
+	for i, n := range formatNames {
+		if strings.EqualFold(name, n) {
+			return Format(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown format %q, expected one of %s", name, strings.Join(formatNames, ", "))
+}
+
+// FormatOf returns the format of a file name by its extension.
+func FormatOf(path string) (Format, bool) {
+	format, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+type Options struct {
+	// ANSI colors for the Text format
+	Color bool
+	// matches of Highlight are emphasized in colored text and HTML
+	Highlight *filter.Matcher
+	// HTML: title of the page and the background and text colors of the
+	// n-th row, nil alternates grays
+	Title     string
+	RowColors func(e model.LogEntry, n int) (bg, fg tcell.Color)
+}
+
+// Writer writes entries in one format. Context entries are shown around
+// matches, separators divide groups of them like in grep.
+type Writer interface {
+	Write(e model.LogEntry, context bool) error
+	Separator() error
+	// Flush writes buffered output, formats that need all entries wait for Close
+	Flush() error
+	// Close completes the output, it must be called when done. It doesn't
+	// close the underlying writer.
+	Close() error
+}
+
+func NewWriter(w io.Writer, format Format, opts Options) Writer {
+	buf := bufio.NewWriterSize(w, 64<<10)
+	switch format {
+	case JSONL:
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		return &jsonWriter{buf: buf, enc: enc}
+	case JSON:
+		w := &jsonArrayWriter{buf: buf}
+		w.enc = json.NewEncoder(&w.record)
+		w.enc.SetEscapeHTML(false)
+		return w
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(buf), buf: buf}
+	case PCAP:
+		return &pcapWriter{buf: buf}
+	case HTML:
+		return &htmlWriter{buf: buf, opts: opts}
+	case Plain:
+		opts.Color = false
+	}
+	return &textWriter{buf: buf, opts: opts}
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiMatch = "\x1b[1;30;43m"
+	ansiSep   = "\x1b[36m"
+)
+
+type textWriter struct {
+	buf  *bufio.Writer
+	opts Options
+}
+
+func (w *textWriter) Write(e model.LogEntry, context bool) error {
+	line := e.RawLine()
+	if !w.opts.Color {
+		w.buf.WriteString(line)
+		return w.buf.WriteByte('\n')
+	}
+
+	if context {
+		w.buf.WriteString(ansiDim + line + ansiReset + "\n")
+		return nil
+	}
+
+	// the level keeps its color, matches are emphasized anywhere in the line
+	color := ""
+	if e.Level != "" {
+		r, g, b := e.LevelColor.RGB()
+		color = fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b)
+	}
+
+	var spans [][]int
+	if w.opts.Highlight != nil {
+		spans = w.opts.Highlight.Spans(line)
+	}
+
+	w.buf.WriteString(color)
+	last := 0
+	for _, span := range spans {
+		w.buf.WriteString(line[last:span[0]])
+		w.buf.WriteString(ansiMatch + line[span[0]:span[1]] + ansiReset + color)
+		last = span[1]
+	}
+	w.buf.WriteString(line[last:])
+	if color != "" {
+		w.buf.WriteString(ansiReset)
+	}
+	return w.buf.WriteByte('\n')
+}
+
+func (w *textWriter) Separator() error {
+	if w.opts.Color {
+		_, err := w.buf.WriteString(ansiSep + "--" + ansiReset + "\n")
+		return err
+	}
+	_, err := w.buf.WriteString("--\n")
+	return err
+}
+
+func (w *textWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *textWriter) Close() error {
+	return w.buf.Flush()
+}
+
+// Record is an entry as written to JSON lines.
+type Record struct {
+	Index     string `json:"index"`
+	Time      string `json:"time,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Level     string `json:"level,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Message   string `json:"message"`
+	Line      string `json:"line"`
+	Context   bool   `json:"context,omitempty"`
+}
+
+func NewRecord(e model.LogEntry, context bool) Record {
+	return Record{
+		Index:     e.Index,
+		Time:      formatTime(e.Time),
+		Timestamp: e.Timestamp,
+		Level:     e.Level,
+		CallID:    e.CallID,
+		Source:    e.Source,
+		Message:   e.Message,
+		Line:      e.RawLine(),
+		Context:   context,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+type jsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *jsonWriter) Write(e model.LogEntry, context bool) error {
+	return w.enc.Encode(NewRecord(e, context))
+}
+
+func (w *jsonWriter) Separator() error {
+	return nil
+}
+
+func (w *jsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *jsonWriter) Close() error {
+	return w.buf.Flush()
+}
+
+// jsonArrayWriter writes the records as elements of one array, one per line
+type jsonArrayWriter struct {
+	buf    *bufio.Writer
+	enc    *json.Encoder
+	record bytes.Buffer
+	n      int
+}
+
+func (w *jsonArrayWriter) Write(e model.LogEntry, context bool) error {
+	w.record.Reset()
+	if err := w.enc.Encode(NewRecord(e, context)); err != nil {
+		return err
+	}
+
+	if w.n == 0 {
+		w.buf.WriteString("[\n  ")
+	} else {
+		w.buf.WriteString(",\n  ")
+	}
+	w.n++
+	_, err := w.buf.Write(bytes.TrimSuffix(w.record.Bytes(), []byte{'\n'}))
+	return err
+}
+
+func (w *jsonArrayWriter) Separator() error {
+	return nil
+}
+
+func (w *jsonArrayWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *jsonArrayWriter) Close() error {
+	if w.n == 0 {
+		w.buf.WriteString("[]\n")
+	} else {
+		w.buf.WriteString("\n]\n")
+	}
+	return w.buf.Flush()
+}
+
+var csvHeader = []string{"index", "time", "level", "call_id", "source", "message", "context"}
+
+type csvWriter struct {
+	w      *csv.Writer
+	buf    *bufio.Writer
+	header bool
+}
+
+func (w *csvWriter) Write(e model.LogEntry, context bool) error {
+	if !w.header {
+		w.header = true
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	ctx := ""
+	if context {
+		ctx = "true"
+	}
+	return w.w.Write([]string{e.Index, formatTime(e.Time), e.Level, e.CallID, e.Source, e.Message, ctx})
+}
+
+func (w *csvWriter) Separator() error {
+	return nil
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+// pcapWriter keeps the entries, the packets of a call are only known
+// from all of its messages
+type pcapWriter struct {
+	buf     *bufio.Writer
+	entries entries
+}
+
+// entries are the written entries by their position
+type entries []model.LogEntry
+
+func (e entries) Get(pos int) (model.LogEntry, bool) {
+	if pos < 0 || pos >= len(e) {
+		return model.LogEntry{}, false
+	}
+	return e[pos], true
+}
+
+func (w *pcapWriter) Write(e model.LogEntry, context bool) error {
+	if !context {
+		w.entries = append(w.entries, e)
+	}
+	return nil
+}
+
+func (w *pcapWriter) Separator() error {
+	return nil
+}
+
+func (w *pcapWriter) Flush() error {
+	return nil
+}
+
+func (w *pcapWriter) Close() error {
+	positions := make([]int, len(w.entries))
+	for i := range positions {
+		positions[i] = i
+	}
+	if _, err := pcap.WriteSIP(w.buf, w.entries, positions); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
@@ -0,0 +1,183 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/model"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	invite = `INVITE sip:2@10.0.0.2 SIP/2.0\r\nVia: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1\r\nFrom: <sip:1@10.0.0.1>;tag=1\r\nTo: <sip:2@10.0.0.2>\r\nCall-ID: c1\r\nCSeq: 1 INVITE\r\n`
+	ok     = `SIP/2.0 200 OK\r\nVia: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1\r\nFrom: <sip:1@10.0.0.1>;tag=1\r\nTo: <sip:2@10.0.0.2>;tag=2\r\nCall-ID: c1\r\nCSeq: 1 INVITE\r\n`
+
+	testTime = time.Date(2025, 12, 4, 14, 30, 0, 0, time.UTC)
+)
+
+// written are the entries each writer gets, a separator comes after the
+// second
+var written = []struct {
+	e       model.LogEntry
+	context bool
+}{
+	{model.LogEntry{Index: "1", Timestamp: "2025-12-04 14:30:00", Time: testTime, Level: "INFO",
+		LevelColor: tcell.NewRGBColor(1, 2, 3), CallID: "c1", Source: "a.log",
+		Message: "Sent SIP: " + invite, OriginalMessage: "[INFO] Sent SIP: " + invite}, false},
+	{model.LogEntry{Index: "2", Message: "trying, \"again\"", OriginalMessage: "trying, \"again\""}, true},
+	{model.LogEntry{Index: "5", Timestamp: "2025-12-04 14:30:00", Time: testTime, Level: "INFO",
+		LevelColor: tcell.NewRGBColor(1, 2, 3), CallID: "c1", Source: "a.log",
+		Message: "Received SIP: " + ok, OriginalMessage: "[INFO] Received SIP: " + ok}, false},
+}
+
+func write(t *testing.T, format Format, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf, format, opts)
+	for i, entry := range written {
+		if err := w.Write(entry.e, entry.context); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			if err := w.Separator(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriters(t *testing.T) {
+	highlight, err := filter.Compile("invite", filter.ModeText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line1 := "[2025-12-04 14:30:00] [INFO] Sent SIP: " + invite
+	line3 := "[2025-12-04 14:30:00] [INFO] Received SIP: " + ok
+
+	tests := []struct {
+		name   string
+		format Format
+		opts   Options
+		want   string
+	}{
+		{"text", Text, Options{}, line1 + "\ntrying, \"again\"\n--\n" + line3 + "\n"},
+		{"plain ignores colors", Plain, Options{Color: true}, line1 + "\ntrying, \"again\"\n--\n" + line3 + "\n"},
+		{"colored text", Text, Options{Color: true, Highlight: highlight},
+			"\x1b[38;2;1;2;3m[2025-12-04 14:30:00] [INFO] Sent SIP: \x1b[1;30;43mINVITE\x1b[0m\x1b[38;2;1;2;3m" +
+				` sip:2@10.0.0.2 SIP/2.0\r\nVia: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1\r\nFrom: <sip:1@10.0.0.1>;tag=1\r\nTo: <sip:2@10.0.0.2>\r\nCall-ID: c1\r\nCSeq: 1 ` +
+				"\x1b[1;30;43mINVITE\x1b[0m\x1b[38;2;1;2;3m" + `\r\n` + "\x1b[0m\n" +
+				"\x1b[2mtrying, \"again\"\x1b[0m\n" +
+				"\x1b[36m--\x1b[0m\n" +
+				"\x1b[38;2;1;2;3m[2025-12-04 14:30:00] [INFO] Received SIP: " + ok[:len(ok)-len(`INVITE\r\n`)] +
+				"\x1b[1;30;43mINVITE\x1b[0m\x1b[38;2;1;2;3m" + `\r\n` + "\x1b[0m\n"},
+		{"JSON lines", JSONL, Options{},
+			`{"index":"1","time":"2025-12-04T14:30:00Z","timestamp":"2025-12-04 14:30:00","level":"INFO","call_id":"c1","source":"a.log","message":"Sent SIP: ` + jsonEscape(invite) + `","line":"` + jsonEscape(line1) + `"}` + "\n" +
+				`{"index":"2","message":"trying, \"again\"","line":"trying, \"again\"","context":true}` + "\n" +
+				`{"index":"5","time":"2025-12-04T14:30:00Z","timestamp":"2025-12-04 14:30:00","level":"INFO","call_id":"c1","source":"a.log","message":"Received SIP: ` + jsonEscape(ok) + `","line":"` + jsonEscape(line3) + `"}` + "\n"},
+		{"CSV", CSV, Options{},
+			"index,time,level,call_id,source,message,context\n" +
+				"1,2025-12-04T14:30:00Z,INFO,c1,a.log,Sent SIP: " + invite + ",\n" +
+				"2,,,,,\"trying, \"\"again\"\"\",true\n" +
+				"5,2025-12-04T14:30:00Z,INFO,c1,a.log,Received SIP: " + ok + ",\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := write(t, tt.format, tt.opts); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// jsonEscape escapes the backslashes of the escaped line breaks in logs
+func jsonEscape(s string) string {
+	return string(bytes.ReplaceAll([]byte(s), []byte(`\`), []byte(`\\`)))
+}
+
+func TestJSONWriter(t *testing.T) {
+	var records []Record
+	if err := json.Unmarshal([]byte(write(t, JSON, Options{})), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(written) {
+		t.Fatalf("%d records, want %d", len(records), len(written))
+	}
+	for i, entry := range written {
+		if want := NewRecord(entry.e, entry.context); records[i] != want {
+			t.Errorf("record %d = %+v, want %+v", i, records[i], want)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, JSON, Options{})
+	if err := w.Close(); err != nil || buf.String() != "[]\n" {
+		t.Errorf("empty export = %q, %v", buf.String(), err)
+	}
+}
+
+func TestPCAPWriter(t *testing.T) {
+	data := []byte(write(t, PCAP, Options{}))
+	if len(data) < 24 || binary.LittleEndian.Uint32(data) != 0xa1b2c3d4 {
+		t.Fatalf("no pcap header in %q", data)
+	}
+
+	// context entries are left out, the messages of the others are packets
+	var payloads []string
+	for data = data[24:]; len(data) >= 16; {
+		size := binary.LittleEndian.Uint32(data[8:])
+		packet := data[16 : 16+size]
+		// Ethernet, IPv4 and UDP headers
+		payloads = append(payloads, string(packet[14+20+8:]))
+		data = data[16+size:]
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("wrote %d packets, want 2", len(payloads))
+	}
+	if !bytes.HasPrefix([]byte(payloads[0]), []byte("INVITE sip:2@10.0.0.2 SIP/2.0\r\n")) ||
+		!bytes.HasPrefix([]byte(payloads[1]), []byte("SIP/2.0 200 OK\r\n")) {
+		t.Errorf("packets %q", payloads)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	for _, name := range Names() {
+		format, err := ParseFormat(name)
+		if err != nil || format.String() != name {
+			t.Errorf("ParseFormat(%q) = %v, %v", name, format, err)
+		}
+	}
+	if format, err := ParseFormat("JSONL"); err != nil || format != JSONL {
+		t.Errorf("ParseFormat(JSONL) = %v, %v", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("unknown format parsed")
+	}
+
+	for path, want := range map[string]Format{
+		"out.log":   Plain,
+		"out.TXT":   Plain,
+		"a.b.jsonl": JSONL,
+		"calls.csv": CSV,
+		"sip.pcap":  PCAP,
+		"rows.JSON": JSON,
+		"page.htm":  HTML,
+	} {
+		if got, ok := FormatOf(path); !ok || got != want {
+			t.Errorf("FormatOf(%q) = %v, %v, want %v", path, got, ok, want)
+		}
+	}
+	if _, ok := FormatOf("out.xml"); ok {
+		t.Error("format of .xml found")
+	}
+}
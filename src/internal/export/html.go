@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"gofly-cli/internal/model"
+	"html"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { background: #1e1e1e; color: #ddd; font: 13px monospace; margin: 1em; }
+table { border-collapse: collapse; width: 100%%; }
+th { color: #ff0; text-align: left; padding: 2px 6px; }
+td { padding: 1px 6px; vertical-align: top; white-space: pre-wrap; word-break: break-all; }
+td.nowrap { white-space: nowrap; }
+td.level { text-align: center; }
+tr.context td { opacity: 0.55; }
+tr.separator td { color: #0aa; }
+mark { background: #ff0; color: #000; }
+</style>
+</head>
+<body>
+<h3>%s</h3>
+<table>
+<tr><th>Idx</th><th>Time</th><th>Level</th><th>Message</th></tr>
+`
+
+const htmlFoot = `</table>
+</body>
+</html>
+`
+
+// htmlWriter writes a table like the log table of the viewer
+type htmlWriter struct {
+	buf     *bufio.Writer
+	opts    Options
+	started bool
+	rows    int
+}
+
+func (w *htmlWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	title := w.opts.Title
+	if title == "" {
+		title = "gofly-cli export"
+	}
+	title = html.EscapeString(title)
+	fmt.Fprintf(w.buf, htmlHead, title, title)
+}
+
+func (w *htmlWriter) Write(e model.LogEntry, context bool) error {
+	w.start()
+
+	bg, fg := tcell.NewHexColor(0x1E1E1E), tcell.ColorWhite
+	if w.rows%2 == 1 {
+		bg = tcell.NewHexColor(0x2D2D2D)
+	}
+	if w.opts.RowColors != nil {
+		bg, fg = w.opts.RowColors(e, w.rows)
+	}
+	w.rows++
+
+	class := ""
+	if context {
+		class = ` class="context"`
+	}
+	fmt.Fprintf(w.buf, `<tr%s style="background: %s; color: %s">`, class, cssColor(bg), cssColor(fg))
+	fmt.Fprintf(w.buf, `<td class="nowrap">%s</td><td class="nowrap">%s</td>`,
+		html.EscapeString(e.Index), html.EscapeString(e.Timestamp))
+	fmt.Fprintf(w.buf, `<td class="nowrap level" style="color: %s">%s</td>`,
+		cssColor(e.LevelColor), html.EscapeString(e.Level))
+	w.buf.WriteString("<td>")
+	w.highlight(e.Message, context)
+	_, err := w.buf.WriteString("</td></tr>\n")
+	return err
+}
+
+// highlight writes text with the matches of Options.Highlight marked
+func (w *htmlWriter) highlight(text string, context bool) {
+	var spans [][]int
+	if w.opts.Highlight != nil && !context {
+		spans = w.opts.Highlight.Spans(text)
+	}
+
+	last := 0
+	for _, span := range spans {
+		w.buf.WriteString(html.EscapeString(text[last:span[0]]))
+		w.buf.WriteString("<mark>" + html.EscapeString(text[span[0]:span[1]]) + "</mark>")
+		last = span[1]
+	}
+	w.buf.WriteString(html.EscapeString(text[last:]))
+}
+
+func (w *htmlWriter) Separator() error {
+	w.start()
+	_, err := w.buf.WriteString(`<tr class="separator"><td colspan="4">--</td></tr>` + "\n")
+	return err
+}
+
+func (w *htmlWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *htmlWriter) Close() error {
+	w.start()
+	w.buf.WriteString(htmlFoot)
+	return w.buf.Flush()
+}
+
+func cssColor(c tcell.Color) string {
+	if c.Hex() < 0 {
+		return "inherit"
+	}
+	return fmt.Sprintf("#%06x", c.Hex())
+}
@@ -0,0 +1,70 @@
+package filter
+
+import "gofly-cli/internal/model"
+
+// Exclusion is a negative filter: entries it matches are hidden.
+type Exclusion struct {
+	Matcher    *Matcher
+	Enabled    bool
+	Suppressed int
+}
+
+type ExclusionList struct {
+	Items []*Exclusion
+	// Hidden counts entries hidden by at least one exclusion
+	Hidden int
+}
+
+func (l *ExclusionList) Add(matcher *Matcher) *Exclusion {
+	excl := &Exclusion{
+		Matcher: matcher,
+		Enabled: true,
+	}
+	l.Items = append(l.Items, excl)
+
+	return excl
+}
+
+func (l *ExclusionList) Remove(i int) {
+	if i < 0 || i >= len(l.Items) {
+		return
+	}
+	l.Items = append(l.Items[:i], l.Items[i+1:]...)
+}
+
+// Check reports whether e is hidden by any enabled exclusion and adds it
+// to the suppressed counter of every exclusion it matches.
+func (l *ExclusionList) Check(e model.LogEntry) bool {
+	excluded := false
+	for _, excl := range l.Items {
+		if excl.Enabled && excl.Matcher.MatchEntry(e) {
+			excl.Suppressed++
+			excluded = true
+		}
+	}
+	if excluded {
+		l.Hidden++
+	}
+	return excluded
+}
+
+// Uncount takes back what Check counted for e, e.g. when e is dropped.
+func (l *ExclusionList) Uncount(e model.LogEntry) {
+	excluded := false
+	for _, excl := range l.Items {
+		if excl.Enabled && excl.Matcher.MatchEntry(e) {
+			excl.Suppressed--
+			excluded = true
+		}
+	}
+	if excluded {
+		l.Hidden--
+	}
+}
+
+func (l *ExclusionList) ResetCounts() {
+	l.Hidden = 0
+	for _, excl := range l.Items {
+		excl.Suppressed = 0
+	}
+}
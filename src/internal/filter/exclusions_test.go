@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"gofly-cli/internal/model"
+	"testing"
+)
+
+func TestExclusionCounts(t *testing.T) {
+	var l ExclusionList
+	noise, _ := Compile("noise", ModeText)
+	keepalive, _ := Compile("keepalive", ModeText)
+	l.Add(noise)
+	l.Add(keepalive)
+
+	log := []model.LogEntry{
+		{Message: "noise"},
+		{Message: "noise keepalive"},
+		{Message: "call"},
+	}
+	for _, e := range log {
+		l.Check(e)
+	}
+	if l.Hidden != 2 || l.Items[0].Suppressed != 2 || l.Items[1].Suppressed != 1 {
+		t.Errorf("hidden %d, suppressed %d and %d", l.Hidden, l.Items[0].Suppressed, l.Items[1].Suppressed)
+	}
+
+	for _, e := range log[1:] {
+		l.Uncount(e)
+	}
+	if l.Hidden != 1 || l.Items[0].Suppressed != 1 || l.Items[1].Suppressed != 0 {
+		t.Errorf("after uncount hidden %d, suppressed %d and %d", l.Hidden, l.Items[0].Suppressed, l.Items[1].Suppressed)
+	}
+}
@@ -0,0 +1,127 @@
+package filter
+
+import (
+	"gofly-cli/internal/model"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ListMode decides how enabled match expressions affect the visible rows.
+type ListMode int
+
+const (
+	ShowAny ListMode = iota
+	ShowAll
+	HighlightOnly
+)
+
+func (m ListMode) String() string {
+	switch m {
+	case ShowAll:
+		return "all"
+	case HighlightOnly:
+		return "highlight"
+	default:
+		return "any"
+	}
+}
+
+// Next returns the mode that follows m, wrapping around.
+func (m ListMode) Next() ListMode {
+	return (m + 1) % 3
+}
+
+type Expression struct {
+	Name    string
+	Matcher *Matcher
+	Color   tcell.Color
+	Enabled bool
+	Hits    int
+}
+
+type ExpressionList struct {
+	Items []*Expression
+	Mode  ListMode
+}
+
+func (l *ExpressionList) Add(name string, matcher *Matcher, color tcell.Color) *Expression {
+	if name == "" {
+		name = matcher.Expr
+	}
+
+	expr := &Expression{
+		Name:    name,
+		Matcher: matcher,
+		Color:   color,
+		Enabled: true,
+	}
+	l.Items = append(l.Items, expr)
+
+	return expr
+}
+
+func (l *ExpressionList) Remove(i int) {
+	if i < 0 || i >= len(l.Items) {
+		return
+	}
+	l.Items = append(l.Items[:i], l.Items[i+1:]...)
+}
+
+func (l *ExpressionList) Enabled() []*Expression {
+	var result []*Expression
+	for _, expr := range l.Items {
+		if expr.Enabled {
+			result = append(result, expr)
+		}
+	}
+	return result
+}
+
+// Visible reports whether e passes the enabled expressions according to Mode.
+// Without enabled expressions, or in HighlightOnly mode, every entry passes.
+func (l *ExpressionList) Visible(e model.LogEntry) bool {
+	if l.Mode == HighlightOnly {
+		return true
+	}
+
+	active := 0
+	matched := 0
+	for _, expr := range l.Items {
+		if !expr.Enabled {
+			continue
+		}
+		active++
+		if expr.Matcher.MatchEntry(e) {
+			if l.Mode == ShowAny {
+				return true
+			}
+			matched++
+		}
+	}
+
+	return active == 0 || (l.Mode == ShowAll && matched == active)
+}
+
+// Count adds e to the hit counter of every expression it matches.
+func (l *ExpressionList) Count(e model.LogEntry) {
+	for _, expr := range l.Items {
+		if expr.Matcher.MatchEntry(e) {
+			expr.Hits++
+		}
+	}
+}
+
+// Uncount removes e from the hit counters, e.g. when it is dropped from memory.
+func (l *ExpressionList) Uncount(e model.LogEntry) {
+	for _, expr := range l.Items {
+		if expr.Matcher.MatchEntry(e) {
+			expr.Hits--
+		}
+	}
+}
+
+func (l *ExpressionList) ResetHits() {
+	for _, expr := range l.Items {
+		expr.Hits = 0
+	}
+}
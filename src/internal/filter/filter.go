@@ -0,0 +1,107 @@
+package filter
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"regexp"
+	"strings"
+)
+
+type Mode int
+
+const (
+	ModeText Mode = iota
+	ModeRegex
+)
+
+// RegexPrefix forces regex mode for a single expression, e.g. "re:\b(4|5)\d\d\b".
+const RegexPrefix = "re:"
+
+func (m Mode) String() string {
+	if m == ModeRegex {
+		return "regex"
+	}
+	return "text"
+}
+
+// Matcher is a compiled filter expression. Text expressions match
+// case-insensitive substrings, regex expressions use Go RE2 syntax.
+type Matcher struct {
+	Expr  string
+	Mode  Mode
+	lower string
+	re    *regexp.Regexp
+}
+
+func Compile(expr string, mode Mode) (*Matcher, error) {
+	expr, mode = splitPrefix(expr, mode)
+	if expr == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	m := &Matcher{Expr: expr, Mode: mode}
+
+	var err error
+	if mode == ModeRegex {
+		m.re, err = regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return m, nil
+	}
+
+	// the regexp is only used for highlighting: it works on the original
+	// text, so spans stay correct when case folding changes byte lengths
+	m.lower = strings.ToLower(expr)
+	m.re, err = regexp.Compile("(?i)" + regexp.QuoteMeta(expr))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func splitPrefix(expr string, mode Mode) (string, Mode) {
+	if rest, ok := strings.CutPrefix(expr, RegexPrefix); ok {
+		return rest, ModeRegex
+	}
+	return expr, mode
+}
+
+func (m *Matcher) Match(text string) bool {
+	if text == "" {
+		return false
+	}
+	if m.Mode == ModeRegex {
+		return m.re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), m.lower)
+}
+
+// Text returns the lower case text of a text expression, ok is false
+// for regex expressions.
+func (m *Matcher) Text() (string, bool) {
+	return m.lower, m.Mode == ModeText
+}
+
+func (m *Matcher) MatchEntry(e model.LogEntry) bool {
+	return m.Match(e.Message) ||
+		m.Match(e.Timestamp) ||
+		m.Match(e.Level) ||
+		m.Match(e.OriginalMessage)
+}
+
+// Spans returns byte offsets [start, end) of every non-empty match in text.
+func (m *Matcher) Spans(text string) [][]int {
+	if text == "" {
+		return nil
+	}
+
+	spans := m.re.FindAllStringIndex(text, -1)
+	result := spans[:0]
+	for _, s := range spans {
+		if s[1] > s[0] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
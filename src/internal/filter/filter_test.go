@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		mode  Mode
+		text  string
+		match bool
+		spans string
+	}{
+		{"text", "invite", ModeText, "INVITE sip:a, invite again", true, "[[0 6] [14 20]]"},
+		{"text is not a regex", "a.c", ModeText, "abc a.c", true, "[[4 7]]"},
+		{"no match", "bye", ModeText, "INVITE", false, "[]"},
+		{"empty text", "bye", ModeText, "", false, "[]"},
+		{"case folding changing lengths", "k", ModeText, "K-k", true, "[[0 3] [4 5]]"},
+		{"regex", `\b(4|5)\d\d\b`, ModeRegex, "SIP/2.0 486 Busy, 2000", true, "[[8 11]]"},
+		{"regex prefix", `re:^BYE|ACK$`, ModeText, "BYE sip:b ACK", true, "[[0 3] [10 13]]"},
+		{"slashes are text", "/api/", ModeText, "GET /API/v1 /ap", true, "[[4 9]]"},
+		{"empty regex matches", "x*", ModeRegex, "axxb", true, "[[1 3]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.expr, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Match(tt.text); got != tt.match {
+				t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.match)
+			}
+			if got := fmt.Sprint(m.Spans(tt.text)); got != tt.spans {
+				t.Errorf("Spans(%q) = %s, want %s", tt.text, got, tt.spans)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expr string
+		mode Mode
+		want Mode
+		ok   bool
+	}{
+		{"invite", ModeText, ModeText, true},
+		{"invite", ModeRegex, ModeRegex, true},
+		{"re:a|b", ModeText, ModeRegex, true},
+		{"/a|b/", ModeText, ModeText, true},
+		{"//", ModeText, ModeText, true},
+		{"(", ModeText, ModeText, true},
+		{"(", ModeRegex, 0, false},
+		{"re:(", ModeText, 0, false},
+		{"", ModeText, 0, false},
+		{"re:", ModeText, 0, false},
+	}
+	for _, tt := range tests {
+		m, err := Compile(tt.expr, tt.mode)
+		if (err == nil) != tt.ok || err == nil && m.Mode != tt.want {
+			t.Errorf("Compile(%q, %v) = %v, %v", tt.expr, tt.mode, m, err)
+		}
+	}
+}
+
+func TestMatchEntry(t *testing.T) {
+	e := model.LogEntry{
+		Timestamp:       "2025-12-04 14:30:00",
+		Level:           "WARN",
+		Message:         "retry",
+		OriginalMessage: "[WARN] retry",
+	}
+	for expr, want := range map[string]bool{
+		"14:30":      true,
+		"warn":       true,
+		"[warn] re":  true,
+		"retry":      true,
+		"re:^retry$": true,
+		"error":      false,
+	} {
+		m, err := Compile(expr, ModeText)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.MatchEntry(e); got != want {
+			t.Errorf("MatchEntry with %q = %v, want %v", expr, got, want)
+		}
+	}
+}
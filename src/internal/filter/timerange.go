@@ -0,0 +1,158 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeRange limits entries by their parsed timestamp. A zero From or To
+// leaves that side of the range open.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+	// set for "last DURATION", From follows the newest entry
+	Last time.Duration
+}
+
+type timeLayout struct {
+	layout string
+	// the span a value covers, an upper bound includes all of it
+	precision time.Duration
+}
+
+var dateLayouts = []timeLayout{
+	{"2006-01-02 15:04:05", time.Second},
+	{"2006-01-02 15:04", time.Minute},
+	{"2006-01-02T15:04:05", time.Second},
+	{"2006-01-02", 24 * time.Hour},
+}
+
+var clockLayouts = []timeLayout{
+	{"15:04:05", time.Second},
+	{"15:04", time.Minute},
+}
+
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// Contains reports whether t is inside the range. Entries without a parsed
+// timestamp never match an active range.
+func (r TimeRange) Contains(t time.Time) bool {
+	if r.IsZero() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && t.After(r.To) {
+		return false
+	}
+	return true
+}
+
+// Slide moves From of a "last DURATION" range up to newest minus the
+// duration and reports whether it moved.
+func (r *TimeRange) Slide(newest time.Time) bool {
+	if r.Last == 0 || newest.IsZero() {
+		return false
+	}
+	from := newest.Add(-r.Last)
+	if !from.After(r.From) {
+		return false
+	}
+	r.From = from
+	return true
+}
+
+func (r TimeRange) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	if r.Last > 0 {
+		return "last " + r.Last.String()
+	}
+
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return format(r.From) + ".." + format(r.To)
+}
+
+// ParseTimeRange parses "last 5m", "FROM..TO", "FROM.." or "..TO".
+// Relative ranges and time-of-day values are resolved against ref,
+// which is the current time online or the newest entry of a file.
+func ParseTimeRange(spec string, ref time.Time) (TimeRange, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return TimeRange{}, nil
+	}
+
+	if last, ok := strings.CutPrefix(spec, "last"); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(last))
+		if err != nil {
+			return TimeRange{}, fmt.Errorf("invalid duration %q", strings.TrimSpace(last))
+		}
+		return TimeRange{From: ref.Add(-d), Last: d}, nil
+	}
+
+	fromText, toText, found := strings.Cut(spec, "..")
+	if !found {
+		return TimeRange{}, fmt.Errorf("invalid time range %q, expected FROM..TO or last DURATION", spec)
+	}
+
+	var r TimeRange
+	var err error
+	if strings.TrimSpace(fromText) != "" {
+		if r.From, err = ParseTime(fromText, ref); err != nil {
+			return TimeRange{}, err
+		}
+	}
+	if strings.TrimSpace(toText) != "" {
+		to, precision, err := parseTime(toText, ref)
+		if err != nil {
+			return TimeRange{}, err
+		}
+		// "..14:35" includes 14:35:59
+		r.To = to.Add(precision - time.Nanosecond)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		return TimeRange{}, fmt.Errorf("time range ends before it starts")
+	}
+
+	return r, nil
+}
+
+// ParseTime parses an absolute date and time or a time of day on the date of ref.
+func ParseTime(text string, ref time.Time) (time.Time, error) {
+	t, _, err := parseTime(text, ref)
+	return t, err
+}
+
+// parseTime is ParseTime returning the precision of text as well
+func parseTime(text string, ref time.Time) (time.Time, time.Duration, error) {
+	text = strings.TrimSpace(text)
+	loc := ref.Location()
+
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l.layout, text, loc); err == nil {
+			return t, l.precision, nil
+		}
+	}
+
+	for _, l := range clockLayouts {
+		if t, err := time.ParseInLocation(l.layout, text, loc); err == nil {
+			y, m, d := ref.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), l.precision, nil
+		}
+	}
+
+	return time.Time{}, 0, fmt.Errorf("invalid time %q", text)
+}
@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	ref := time.Date(2025, 12, 4, 14, 30, 10, 0, time.UTC)
+	at := func(text string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04:05.999999999", text)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		spec string
+		want TimeRange
+		ok   bool
+	}{
+		{"", TimeRange{}, true},
+		{"last 5m", TimeRange{From: at("2025-12-04 14:25:10"), Last: 5 * time.Minute}, true},
+		{"last1h", TimeRange{From: at("2025-12-04 13:30:10"), Last: time.Hour}, true},
+		{"14:00..14:35", TimeRange{From: at("2025-12-04 14:00:00"), To: at("2025-12-04 14:35:59.999999999")}, true},
+		{"14:00:05..14:00:05", TimeRange{From: at("2025-12-04 14:00:05"), To: at("2025-12-04 14:00:05.999999999")}, true},
+		{"2025-12-03 10:00..", TimeRange{From: at("2025-12-03 10:00:00")}, true},
+		{"..2025-12-03", TimeRange{To: at("2025-12-03 23:59:59.999999999")}, true},
+		{" 2025-12-03T10:00:00 .. 2025-12-03 11:00:00 ", TimeRange{From: at("2025-12-03 10:00:00"), To: at("2025-12-03 11:00:00.999999999")}, true},
+		{"14:35..14:35", TimeRange{From: at("2025-12-04 14:35:00"), To: at("2025-12-04 14:35:59.999999999")}, true},
+		{"14:36..14:35", TimeRange{}, false},
+		{"last", TimeRange{}, false},
+		{"last 5 minutes", TimeRange{}, false},
+		{"14:00", TimeRange{}, false},
+		{"noon..", TimeRange{}, false},
+		{"..25:00", TimeRange{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeRange(tt.spec, ref)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseTimeRange(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTimeRange(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestTimeRangeContains(t *testing.T) {
+	ref := time.Date(2025, 12, 4, 14, 30, 0, 0, time.UTC)
+	r, err := ParseTimeRange("14:00..14:35", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Time{}, false},
+		{ref.Add(-31 * time.Minute), false},
+		{ref.Add(-30 * time.Minute), true},
+		{ref.Add(5*time.Minute + 59*time.Second + 999*time.Millisecond), true},
+		{ref.Add(6 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		if got := r.Contains(tt.t); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+
+	if !(TimeRange{}).Contains(time.Time{}) {
+		t.Error("an empty range excludes entries without a timestamp")
+	}
+}
+
+func TestTimeRangeSlide(t *testing.T) {
+	ref := time.Date(2025, 12, 4, 14, 30, 0, 0, time.UTC)
+	r, err := ParseTimeRange("last 1m", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		newest time.Time
+		moved  bool
+		from   time.Time
+	}{
+		{time.Time{}, false, ref.Add(-time.Minute)},
+		{ref.Add(-time.Second), false, ref.Add(-time.Minute)},
+		{ref, false, ref.Add(-time.Minute)},
+		{ref.Add(10 * time.Second), true, ref.Add(-50 * time.Second)},
+		{ref.Add(5 * time.Second), false, ref.Add(-50 * time.Second)},
+	}
+	for _, tt := range tests {
+		if moved := r.Slide(tt.newest); moved != tt.moved || !r.From.Equal(tt.from) {
+			t.Errorf("Slide(%v) = %v with From %v, want %v with %v", tt.newest, moved, r.From, tt.moved, tt.from)
+		}
+	}
+	if got := r.String(); got != "last 1m0s" {
+		t.Errorf("String() = %q", got)
+	}
+
+	fixed, _ := ParseTimeRange("14:00..", ref)
+	if fixed.Slide(ref.Add(time.Hour)) {
+		t.Error("a fixed range slid")
+	}
+}
@@ -0,0 +1,195 @@
+package index
+
+import (
+	"encoding/binary"
+	"gofly-cli/internal/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Chunk indexes consecutive lines, chunks are built concurrently and joined
+// in file order by a Builder.
+type Chunk struct {
+	offsets []int64
+	lines   []line
+	// token -> chunk relative line numbers, ascending
+	tokens map[string][]uint32
+}
+
+func NewChunk() *Chunk {
+	return &Chunk{tokens: make(map[string][]uint32)}
+}
+
+// Add indexes the entry parsed from the line starting at offset.
+func (c *Chunk) Add(offset int64, e model.LogEntry) {
+	n := uint32(len(c.offsets))
+	c.offsets = append(c.offsets, offset)
+	c.lines = append(c.lines, line{e.Time, e.Level, e.CallID})
+
+	add := func(token string) {
+		lines := c.tokens[token]
+		if len(lines) > 0 && lines[len(lines)-1] == n {
+			return
+		}
+		c.tokens[token] = append(lines, n)
+	}
+
+	tokenizeEntry(e, add)
+}
+
+// line holds the parsed fields of a line kept in the index
+type line struct {
+	t      time.Time
+	level  string
+	callID string
+}
+
+// Builder joins chunks into an index.
+type Builder struct {
+	// bound of the token postings kept in memory, 0 means no limit. Beyond
+	// it the index is built without them.
+	MaxTokenBytes int64
+
+	idx        *Index
+	levels     map[string]uint8
+	callIDs    map[string]uint32
+	last       map[string]int
+	postings   map[string][]byte
+	tokenBytes int64
+}
+
+func NewBuilder(key Key) *Builder {
+	return &Builder{
+		idx: &Index{
+			Key:         key,
+			LevelNames:  []string{""},
+			CallIDNames: []string{""},
+		},
+		levels:   map[string]uint8{"": 0},
+		callIDs:  map[string]uint32{"": 0},
+		last:     make(map[string]int),
+		postings: make(map[string][]byte),
+	}
+}
+
+// Add appends the lines of c, which must follow the lines added before.
+func (b *Builder) Add(c *Chunk) {
+	first := len(b.idx.Offsets)
+	idx := b.idx
+
+	for i, e := range c.lines {
+		idx.Offsets = append(idx.Offsets, c.offsets[i])
+
+		var t int64
+		if !e.t.IsZero() {
+			t = e.t.UnixNano()
+		}
+		idx.Times = append(idx.Times, t)
+
+		level, ok := b.levels[e.level]
+		if !ok && len(idx.LevelNames) <= 255 {
+			level = uint8(len(idx.LevelNames))
+			b.levels[e.level] = level
+			idx.LevelNames = append(idx.LevelNames, e.level)
+		}
+		idx.Levels = append(idx.Levels, level)
+
+		callID, ok := b.callIDs[e.callID]
+		if !ok {
+			name := strings.Clone(e.callID)
+			callID = uint32(len(idx.CallIDNames))
+			b.callIDs[name] = callID
+			idx.CallIDNames = append(idx.CallIDNames, name)
+		}
+		idx.CallIDs = append(idx.CallIDs, callID)
+	}
+
+	if b.postings == nil {
+		return
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	for token, lines := range c.tokens {
+		last, seen := b.last[token]
+		if !seen {
+			// the token points into the line, which is not kept
+			token = strings.Clone(token)
+			b.tokenBytes += int64(len(token))
+		}
+		data := b.postings[token]
+		size := len(data)
+		for _, n := range lines {
+			line := first + int(n)
+			data = append(data, buf[:binary.PutUvarint(buf[:], uint64(line-last))]...)
+			last = line
+		}
+		b.postings[token] = data
+		b.last[token] = last
+		b.tokenBytes += int64(len(data) - size)
+	}
+
+	if b.MaxTokenBytes > 0 && b.tokenBytes > b.MaxTokenBytes {
+		// searches of the index scan all lines instead
+		b.postings, b.last = nil, nil
+	}
+}
+
+// Index returns the built index, the builder must not be used afterwards.
+func (b *Builder) Index() *Index {
+	b.idx.Tokens = b.postings
+	return b.idx
+}
+
+// tokenizeEntry calls fn with the tokens of every field a filter matches
+func tokenizeEntry(e model.LogEntry, fn func(string)) {
+	tokenize(e.Timestamp, fn)
+	tokenize(e.OriginalMessage, fn)
+	// removing level tags may join words of the message
+	if !strings.Contains(e.OriginalMessage, e.Message) {
+		tokenize(e.Message, fn)
+	}
+}
+
+// tokenize calls fn with every lower case run of letters and digits in text
+// that is at least two bytes long. Other bytes, including all non-ASCII ones,
+// separate tokens.
+func tokenize(text string, fn func(string)) {
+	// lowering non-ASCII text may give ASCII letters, e.g. for the Kelvin sign
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			text = strings.ToLower(text)
+			break
+		}
+	}
+
+	start := -1
+	upper := false
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) {
+			c := text[i]
+			if c >= 'A' && c <= 'Z' {
+				upper = true
+			}
+			if isTokenByte(c) || c >= 'A' && c <= 'Z' {
+				if start == -1 {
+					start = i
+				}
+				continue
+			}
+		}
+		if start != -1 && i-start >= 2 {
+			if upper {
+				fn(strings.ToLower(text[start:i]))
+			} else {
+				fn(text[start:i])
+			}
+		}
+		start = -1
+		upper = false
+	}
+}
+
+func isTokenByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
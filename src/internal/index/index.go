@@ -0,0 +1,275 @@
+// Package index keeps a sidecar index of a log file: line offsets, parsed
+// timestamps, levels, Call-IDs and the lines every token occurs in. Reopening
+// an indexed file skips parsing, searches read only candidate lines.
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"gofly-cli/internal/config"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Suffix is appended to the log file name to get the sidecar path.
+const Suffix = ".gfidx"
+
+// version changes whenever the format or the tokenization changes
+const version = 1
+
+// hashed at the start and the end of the file
+const hashBlock = 64 << 10
+
+var ErrStale = errors.New("index does not match the file")
+
+// Key identifies the file contents an index was built from.
+type Key struct {
+	Version int
+	Size    int64
+	ModTime int64
+	Hash    uint64
+	// names of the known levels, they decide how lines are parsed
+	Levels string
+}
+
+// KeyOf returns the key of the file at path as it is now.
+func KeyOf(path string) (Key, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Key{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Key{}, err
+	}
+
+	h := fnv.New64a()
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, hashBlock)); err != nil {
+		return Key{}, err
+	}
+	if tail := info.Size() - hashBlock; tail > hashBlock {
+		if _, err := io.Copy(h, io.NewSectionReader(file, tail, hashBlock)); err != nil {
+			return Key{}, err
+		}
+	}
+
+	names := make([]string, len(parser.Levels))
+	for i, level := range parser.Levels {
+		names[i] = level.Name
+	}
+
+	return Key{
+		Version: version,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    h.Sum64(),
+		Levels:  strings.Join(names, ","),
+	}, nil
+}
+
+// Index describes every line of a file. Per line slices are indexed by the
+// line number, starting at 0.
+type Index struct {
+	Key     Key
+	Offsets []int64
+	// unix nanoseconds, 0 when the line has no time
+	Times []int64
+	// into LevelNames and CallIDNames, 0 when the line has none
+	Levels      []uint8
+	LevelNames  []string
+	CallIDs     []uint32
+	CallIDNames []string
+	// line numbers of every token, delta and varint encoded, nil when they
+	// took more memory than the builder was allowed
+	Tokens map[string][]byte
+}
+
+// Lines returns the number of lines in the file.
+func (idx *Index) Lines() int {
+	return len(idx.Offsets)
+}
+
+// Known returns the parsed time, level and Call-ID of line n.
+func (idx *Index) Known(n int) (time.Time, string, string) {
+	var t time.Time
+	if idx.Times[n] != 0 {
+		t = time.Unix(0, idx.Times[n])
+	}
+	return t, idx.LevelNames[idx.Levels[n]], idx.CallIDNames[idx.CallIDs[n]]
+}
+
+// Entry reads line n from r, the indexed file, and builds its entry.
+func (idx *Index) Entry(r io.ReaderAt, n int) (model.LogEntry, error) {
+	end := idx.Key.Size
+	if n+1 < len(idx.Offsets) {
+		end = idx.Offsets[n+1]
+	}
+
+	buf := make([]byte, end-idx.Offsets[n])
+	if _, err := r.ReadAt(buf, idx.Offsets[n]); err != nil && !errors.Is(err, io.EOF) {
+		return model.LogEntry{}, err
+	}
+	line := strings.TrimSuffix(strings.TrimSuffix(string(buf), "\n"), "\r")
+
+	t, level, callID := idx.Known(n)
+	return parser.ParseKnown(line, t, level, callID), nil
+}
+
+// CallLines returns the lines with the Call-ID in ascending order.
+func (idx *Index) CallLines(callID string) []int {
+	id := -1
+	for i, name := range idx.CallIDNames {
+		if i > 0 && name == callID {
+			id = i
+			break
+		}
+	}
+
+	var lines []int
+	for n, c := range idx.CallIDs {
+		if int(c) == id {
+			lines = append(lines, n)
+		}
+	}
+	return lines
+}
+
+// Open returns the stored index of the file at path, ErrStale when it was
+// built from other contents.
+func Open(path string) (*Index, error) {
+	key, err := KeyOf(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// a stale sidecar is reported rather than a missing one in the cache
+	err = os.ErrNotExist
+	for _, sidecar := range sidecarPaths(path) {
+		idx, rerr := read(sidecar, key)
+		if rerr == nil {
+			return idx, nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			err = rerr
+		}
+	}
+	return nil, err
+}
+
+func read(sidecar string, key Key) (*Index, error) {
+	file, err := os.Open(sidecar)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(file))
+
+	// the key comes first, stale indexes are not decoded completely
+	var stored Key
+	if err := dec.Decode(&stored); err != nil {
+		return nil, err
+	}
+	if stored != key {
+		return nil, ErrStale
+	}
+
+	idx := &Index{}
+	if err := dec.Decode(idx); err != nil {
+		return nil, err
+	}
+	if len(idx.Times) != len(idx.Offsets) || len(idx.Levels) != len(idx.Offsets) || len(idx.CallIDs) != len(idx.Offsets) {
+		return nil, fmt.Errorf("corrupt index %s", sidecar)
+	}
+	return idx, nil
+}
+
+// Save stores the index next to the file at path, or in the user cache
+// directory when that is not writable.
+func (idx *Index) Save(path string) error {
+	var err error
+	for _, sidecar := range sidecarPaths(path) {
+		if err = idx.write(sidecar); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (idx *Index) write(sidecar string) error {
+	if err := os.MkdirAll(filepath.Dir(sidecar), 0o755); err != nil {
+		return err
+	}
+
+	// written under a temporary name, a half written index is never opened
+	file, err := os.CreateTemp(filepath.Dir(sidecar), filepath.Base(sidecar)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	w := bufio.NewWriter(file)
+	enc := gob.NewEncoder(w)
+	if err = file.Chmod(0o644); err == nil {
+		err = enc.Encode(idx.Key)
+	}
+	if err == nil {
+		err = enc.Encode(idx)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), sidecar)
+}
+
+// sidecarPaths returns where the index of the file at path may be stored
+func sidecarPaths(path string) []string {
+	paths := []string{path + Suffix}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return paths
+	}
+	dir, err := config.CacheDir()
+	if err != nil {
+		return paths
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(abs))
+	name := fmt.Sprintf("%s-%016x%s", filepath.Base(abs), h.Sum64(), Suffix)
+
+	return append(paths, filepath.Join(dir, "index", name))
+}
+
+// postings decodes the line numbers of a token
+func postings(data []byte, fn func(n int)) {
+	n := 0
+	for len(data) > 0 {
+		delta, size := binary.Uvarint(data)
+		if size <= 0 {
+			return
+		}
+		data = data[size:]
+		n += int(delta)
+		fn(n)
+	}
+}
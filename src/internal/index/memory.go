@@ -0,0 +1,181 @@
+package index
+
+import (
+	"encoding/binary"
+	"gofly-cli/internal/model"
+	"strings"
+)
+
+// Memory is an inverted token index of log entries kept in memory, built
+// incrementally as entries arrive. A trigram index of the tokens finds the
+// ones containing a substring without going through all of them.
+type Memory struct {
+	tokens map[string]*posting
+	// token by id, ids are reassigned when the index is compacted
+	names    []string
+	trigrams map[[3]byte][]uint32
+
+	first, next int
+	// first position when the postings were last compacted
+	compacted int
+	// approximate memory used, see Bytes
+	bytes int64
+}
+
+const (
+	// tokenOverhead approximates the memory of a token besides its text and
+	// postings: its map entry, posting and name
+	tokenOverhead = 96
+	// trigramOverhead approximates the memory of a map entry of trigrams
+	trigramOverhead = 48
+)
+
+type posting struct {
+	id   uint32
+	last int
+	data []byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		tokens:   make(map[string]*posting),
+		trigrams: make(map[[3]byte][]uint32),
+	}
+}
+
+// Add indexes the entry at pos, positions must ascend.
+func (m *Memory) Add(pos int, e model.LogEntry) {
+	m.next = pos + 1
+
+	add := func(token string) {
+		p := m.tokens[token]
+		if p == nil {
+			// the token points into the entry, which may be dropped
+			token = strings.Clone(token)
+			p = &posting{id: uint32(len(m.names))}
+			m.tokens[token] = p
+			m.names = append(m.names, token)
+			m.addTrigrams(token, p.id)
+			m.bytes += tokenOverhead + int64(len(token))
+		} else if len(p.data) > 0 && p.last == pos {
+			return
+		}
+		size := len(p.data)
+		p.data = binary.AppendUvarint(p.data, uint64(pos-p.last))
+		p.last = pos
+		m.bytes += int64(len(p.data) - size)
+	}
+
+	tokenizeEntry(e, add)
+}
+
+func (m *Memory) addTrigrams(token string, id uint32) {
+	for i := 0; i+3 <= len(token); i++ {
+		tri := [3]byte{token[i], token[i+1], token[i+2]}
+		ids := m.trigrams[tri]
+		if len(ids) > 0 && ids[len(ids)-1] == id {
+			continue
+		}
+		if len(ids) == 0 {
+			m.bytes += trigramOverhead
+		}
+		m.trigrams[tri] = append(ids, id)
+		m.bytes += 4
+	}
+}
+
+// Drop forgets the entries before pos. Postings are compacted once more
+// entries were dropped than are left.
+func (m *Memory) Drop(pos int) {
+	if pos <= m.first {
+		return
+	}
+	m.first = pos
+	m.next = max(m.next, pos)
+	if m.first-m.compacted > m.next-m.first {
+		m.compact()
+	}
+}
+
+func (m *Memory) compact() {
+	m.bytes = 0
+	names := m.names[:0]
+	for _, token := range m.names {
+		p := m.tokens[token]
+
+		var data []byte
+		last := 0
+		postings(p.data, func(n int) {
+			if n >= m.first {
+				data = binary.AppendUvarint(data, uint64(n-last))
+				last = n
+			}
+		})
+		if data == nil {
+			delete(m.tokens, token)
+			continue
+		}
+
+		p.id = uint32(len(names))
+		p.data = data
+		names = append(names, token)
+		m.bytes += tokenOverhead + int64(len(token)+len(data))
+	}
+	clear(m.names[len(names):])
+	m.names = names
+
+	m.trigrams = make(map[[3]byte][]uint32)
+	for id, token := range m.names {
+		m.addTrigrams(token, uint32(id))
+	}
+	m.compacted = m.first
+}
+
+// Bytes returns the approximate memory used by the index. Postings of
+// dropped entries count until they are compacted.
+func (m *Memory) Bytes() int64 {
+	return m.bytes
+}
+
+// Search returns the positions of the entries that may contain text,
+// compared case-insensitive, in ascending order. Candidates must still be
+// matched against the entries. ok is false when text has no token the index
+// can narrow the entries by.
+func (m *Memory) Search(text string) ([]int, bool) {
+	return search(m, text, m.first, m.next)
+}
+
+func (m *Memory) postings(token string) []byte {
+	if p := m.tokens[token]; p != nil {
+		return p.data
+	}
+	return nil
+}
+
+func (m *Memory) each(sub string, match func(string) bool, fn func([]byte)) {
+	if len(sub) < 3 {
+		for token, p := range m.tokens {
+			if match(token) {
+				fn(p.data)
+			}
+		}
+		return
+	}
+
+	// the tokens of the rarest trigram of sub are the fewest to check
+	var ids []uint32
+	for i := 0; i+3 <= len(sub); i++ {
+		candidates := m.trigrams[[3]byte{sub[i], sub[i+1], sub[i+2]}]
+		if len(candidates) == 0 {
+			return
+		}
+		if ids == nil || len(candidates) < len(ids) {
+			ids = candidates
+		}
+	}
+	for _, id := range ids {
+		if token := m.names[id]; match(token) {
+			fn(m.tokens[token].data)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package index
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"slices"
+	"testing"
+)
+
+var testEntries = []model.LogEntry{
+	{OriginalMessage: "INVITE sip:alice@example.com SIP/2.0"},
+	{OriginalMessage: "BYE sip:bob@example.org SIP/2.0"},
+	{OriginalMessage: "REGISTER alice timeout=30"},
+	{OriginalMessage: "Call-ID: abc123xyz"},
+	{Timestamp: "2025-12-04 14:30:00", OriginalMessage: "[WARN] retry", Message: "retry"},
+}
+
+var searchTests = []struct {
+	name string
+	text string
+	want []int
+	ok   bool
+}{
+	{"word", "invite", []int{0}, true},
+	{"upper case", "ABC123XYZ", []int{3}, true},
+	{"part of a word", "lic", []int{0, 2}, true},
+	{"short part of a word", "al", []int{0, 2, 3}, true},
+	{"end of a word", "xyz", []int{3}, true},
+	{"whole word", " sip ", []int{0, 1}, true},
+	{"whole word only", " alic ", nil, true},
+	{"suffix and prefix", "sip:ali", []int{0}, true},
+	{"several words", "example.com", []int{0}, true},
+	{"words of different entries", "bob alice", nil, true},
+	{"timestamp", "14:30", []int{4}, true},
+	{"level tag", "warn", []int{4}, true},
+	{"missing", "nothing", nil, true},
+	{"single byte", "a", nil, false},
+	{"separators", " - : ", nil, false},
+}
+
+func TestMemorySearch(t *testing.T) {
+	m := NewMemory()
+	for i, e := range testEntries {
+		m.Add(i, e)
+	}
+
+	for _, tt := range searchTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.Search(tt.text)
+			if ok != tt.ok || !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	chunk := NewChunk()
+	for i, e := range testEntries {
+		chunk.Add(int64(i), e)
+	}
+	builder := NewBuilder(Key{})
+	builder.Add(chunk)
+	idx := builder.Index()
+
+	for _, tt := range searchTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := idx.Search(tt.text)
+			if ok != tt.ok || !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIndexWithoutPostings(t *testing.T) {
+	chunk := NewChunk()
+	for i, e := range testEntries {
+		chunk.Add(int64(i), e)
+	}
+	builder := NewBuilder(Key{})
+	builder.MaxTokenBytes = 10
+	builder.Add(chunk)
+	idx := builder.Index()
+
+	if got, ok := idx.Search("invite"); ok {
+		t.Errorf("search without postings = %v, want no result", got)
+	}
+	if idx.Lines() != len(testEntries) {
+		t.Errorf("lines = %d, want %d", idx.Lines(), len(testEntries))
+	}
+}
+
+func TestMemoryDrop(t *testing.T) {
+	m := NewMemory()
+	for i, e := range testEntries {
+		m.Add(i, e)
+	}
+
+	tests := []struct {
+		drop int
+		text string
+		want []int
+	}{
+		{1, "alice", []int{2}},
+		{1, "sip", []int{1}},
+		// dropping more entries than are left compacts the postings
+		{3, "alice", nil},
+		{3, "lic", nil},
+		{3, "call", []int{3}},
+		{3, "retry", []int{4}},
+	}
+	for _, tt := range tests {
+		m.Drop(tt.drop)
+		if got, ok := m.Search(tt.text); !ok || !slices.Equal(got, tt.want) {
+			t.Errorf("after Drop(%d) Search(%q) = %v, %v, want %v", tt.drop, tt.text, got, ok, tt.want)
+		}
+	}
+
+	m.Add(5, model.LogEntry{OriginalMessage: "alice again"})
+	if got, _ := m.Search("alice"); !slices.Equal(got, []int{5}) {
+		t.Errorf("Search after compaction = %v, want [5]", got)
+	}
+
+	// positions can be skipped past the last added entry
+	m.Drop(100)
+	if got, _ := m.Search("alice"); len(got) != 0 {
+		t.Errorf("Search after skipping = %v, want none", got)
+	}
+	m.Add(100, model.LogEntry{OriginalMessage: "alice"})
+	if got, _ := m.Search("alice"); !slices.Equal(got, []int{100}) {
+		t.Errorf("Search after skipping = %v, want [100]", got)
+	}
+}
+
+func TestMemoryBytes(t *testing.T) {
+	m := NewMemory()
+	if m.Bytes() != 0 {
+		t.Fatalf("empty index uses %d bytes", m.Bytes())
+	}
+
+	var sizes []int64
+	for i := range 1000 {
+		m.Add(i, model.LogEntry{OriginalMessage: fmt.Sprintf("INVITE call-%d from alice", i)})
+		sizes = append(sizes, m.Bytes())
+	}
+	if !slices.IsSorted(sizes) || sizes[0] == 0 {
+		t.Fatalf("index size does not grow with entries: %d .. %d", sizes[0], sizes[len(sizes)-1])
+	}
+
+	// postings of dropped entries count until they are compacted
+	full := m.Bytes()
+	m.Drop(400)
+	if m.Bytes() != full {
+		t.Errorf("size after Drop(400) = %d, want %d", m.Bytes(), full)
+	}
+	m.Drop(900)
+	if m.Bytes() >= sizes[200] {
+		t.Errorf("size of 100 entries after compaction = %d, want less than %d", m.Bytes(), sizes[200])
+	}
+}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// dictionary gives access to the postings of the tokens of an index
+type dictionary interface {
+	// postings returns the encoded positions of token
+	postings(token string) []byte
+	// each calls fn with the postings of every token accepted by match.
+	// Accepted tokens contain sub.
+	each(sub string, match func(string) bool, fn func([]byte))
+}
+
+// Search returns the lines that may contain text, compared case-insensitive,
+// in ascending order. Candidates must still be matched against the line.
+// ok is false when text has no token the index can narrow the lines by.
+func (idx *Index) Search(text string) ([]int, bool) {
+	if idx.Tokens == nil {
+		return nil, false
+	}
+	return search(idx, text, 0, idx.Lines())
+}
+
+func (idx *Index) postings(token string) []byte {
+	return idx.Tokens[token]
+}
+
+func (idx *Index) each(sub string, match func(string) bool, fn func([]byte)) {
+	for token, data := range idx.Tokens {
+		if match(token) {
+			fn(data)
+		}
+	}
+}
+
+// search returns the positions from first to end whose tokens may make up text
+func search(dict dictionary, text string, first, end int) ([]int, bool) {
+	text = strings.ToLower(text)
+
+	var result []uint64
+	used := false
+
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && isTokenByte(text[i]) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start == -1 {
+			continue
+		}
+
+		token := text[start:i]
+		// a token cut by the start or the end of text may be part of a
+		// longer token of the line
+		open := start == 0
+		closed := i < len(text)
+		start = -1
+		if len(token) < 2 {
+			continue
+		}
+
+		set := make([]uint64, (end-first+63)/64)
+		mark := func(data []byte) {
+			postings(data, func(n int) {
+				if n >= first && n < end {
+					set[(n-first)/64] |= 1 << ((n - first) % 64)
+				}
+			})
+		}
+
+		if !open && closed {
+			mark(dict.postings(token))
+		} else {
+			dict.each(token, func(candidate string) bool {
+				switch {
+				case open && !closed:
+					return strings.Contains(candidate, token)
+				case open:
+					return strings.HasSuffix(candidate, token)
+				}
+				return strings.HasPrefix(candidate, token)
+			}, mark)
+		}
+
+		if !used {
+			result, used = set, true
+			continue
+		}
+		for w := range result {
+			result[w] &= set[w]
+		}
+	}
+
+	if !used {
+		return nil, false
+	}
+
+	var found []int
+	for w, word := range result {
+		for word != 0 {
+			found = append(found, first+w*64+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return found, true
+}
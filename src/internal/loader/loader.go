@@ -0,0 +1,329 @@
+// Package loader reads log files in chunks parsed on all cores.
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"gofly-cli/internal/store"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// ChunkSize is the amount of the file handed to one parser at a time.
+var ChunkSize = 4 << 20
+
+// UseIndex enables reading and writing the sidecar index of loaded files.
+var UseIndex = true
+
+// BuildIndex enables building and storing the index of files without one,
+// otherwise UseIndex only reads existing ones.
+var BuildIndex = true
+
+// Options tune a load to the memory of the caller.
+type Options struct {
+	// the budget of the caller, 0 means no limit. With a sidecar index only
+	// the last lines fitting it are read, a new index keeps the postings of
+	// its tokens within a quarter of MaxBytes.
+	MaxEntries int
+	MaxBytes   int64
+	// called before the first entry when a sidecar index is used, with the
+	// number of leading lines that are not read
+	OnIndex func(idx *index.Index, skipped int)
+}
+
+// Progress describes a running or finished load.
+type Progress struct {
+	Bytes   int64
+	Total   int64
+	Lines   int
+	Elapsed time.Duration
+	Done    bool
+	Err     error
+	// the lines were taken from the sidecar index instead of being parsed
+	Indexed bool
+	// leading lines not read because they exceed the memory budget
+	Skipped int
+}
+
+// Fraction returns the share of the file read, from 0 to 1.
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 1
+	}
+	return float64(p.Bytes) / float64(p.Total)
+}
+
+// LinesPerSecond returns the parse rate so far.
+func (p Progress) LinesPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Lines) / p.Elapsed.Seconds()
+}
+
+// ETA estimates the time left from the bytes read so far.
+func (p Progress) ETA() time.Duration {
+	if p.Bytes <= 0 || p.Total <= p.Bytes {
+		return 0
+	}
+	perByte := float64(p.Elapsed) / float64(p.Bytes)
+	return time.Duration(perByte * float64(p.Total-p.Bytes))
+}
+
+type chunk struct {
+	data []byte
+	// file offset and line number of the first line
+	offset int64
+	line   int
+	result chan parsed
+}
+
+type parsed struct {
+	entries []model.LogEntry
+	index   *index.Chunk
+}
+
+// Load parses the file at path line by line. Chunks are parsed concurrently,
+// emit gets their entries in file order, with Source set to path. Index is
+// left to the caller, who knows the position the entries get. progress is
+// called after every chunk and once at the end. Cancelling ctx stops the load.
+//
+// With UseIndex, a valid sidecar index spares parsing the lines and reading
+// those that don't fit the budget of opts, otherwise one is built and stored
+// after a complete load. The index is returned, nil when there is none.
+func Load(ctx context.Context, path string, opts Options, emit func([]model.LogEntry), progress func(Progress)) (*index.Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var idx *index.Index
+	var builder *index.Builder
+	var key index.Key
+	if UseIndex {
+		if idx, err = index.Open(path); err != nil {
+			idx = nil
+			if key, err = index.KeyOf(path); err == nil && BuildIndex {
+				builder = index.NewBuilder(key)
+				builder.MaxTokenBytes = opts.MaxBytes / 4
+			}
+		}
+	}
+
+	var total int64
+	if info, err := file.Stat(); err == nil {
+		total = info.Size()
+	}
+
+	// the lines before skipped are left in the file, the index finds them
+	skipped := 0
+	var from int64
+	if idx != nil {
+		skipped = tail(idx, opts.MaxEntries, opts.MaxBytes)
+		if skipped < idx.Lines() {
+			from = idx.Offsets[skipped]
+		} else {
+			from = idx.Key.Size
+		}
+		if _, err := file.Seek(from, io.SeekStart); err != nil {
+			return nil, err
+		}
+		total -= from
+		if opts.OnIndex != nil {
+			opts.OnIndex(idx, skipped)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := runtime.NumCPU()
+	jobs := make(chan chunk)
+	// chunks in file order, bounded so reading stays close to parsing
+	order := make(chan chunk, 2*workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for c := range jobs {
+				c.result <- parseChunk(c, path, idx, builder != nil)
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(order)
+		defer close(jobs)
+		offset := from
+		line := skipped
+		readErr <- split(ctx, file, func(data []byte) bool {
+			c := chunk{data: data, offset: offset, line: line, result: make(chan parsed, 1)}
+			offset += int64(len(data))
+			line += bytes.Count(data, []byte{'\n'})
+			select {
+			case order <- c:
+			case <-ctx.Done():
+				return false
+			}
+			select {
+			case jobs <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	start := time.Now()
+	p := Progress{Total: total, Indexed: idx != nil, Skipped: skipped}
+	for c := range order {
+		var result parsed
+		select {
+		case result = <-c.result:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		emit(result.entries)
+		if builder != nil {
+			builder.Add(result.index)
+		}
+
+		p.Bytes += int64(len(c.data))
+		p.Lines += len(result.entries)
+		p.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	err = <-readErr
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	p.Elapsed = time.Since(start)
+	p.Done = true
+	p.Err = err
+	if progress != nil {
+		progress(p)
+	}
+
+	if err == nil && builder != nil {
+		idx = builder.Index()
+		// a file written to while loading is indexed on the next open
+		if now, kerr := index.KeyOf(path); kerr == nil && now == key {
+			idx.Save(path)
+		}
+	}
+	return idx, err
+}
+
+// tail returns the first line of the last lines of idx that fit the budget
+func tail(idx *index.Index, maxEntries int, maxBytes int64) int {
+	first := 0
+	if maxEntries > 0 {
+		first = max(idx.Lines()-maxEntries, 0)
+	}
+	if maxBytes <= 0 {
+		return first
+	}
+
+	var size int64
+	end := idx.Key.Size
+	for n := idx.Lines() - 1; n >= first; n-- {
+		size += store.LineSize(int(end - idx.Offsets[n]))
+		if size > maxBytes {
+			return n + 1
+		}
+		end = idx.Offsets[n]
+	}
+	return first
+}
+
+// split reads r in blocks of ChunkSize cut after the last line break,
+// so no line spans two chunks
+func split(ctx context.Context, r io.Reader, handle func([]byte) bool) error {
+	var carry []byte
+	for ctx.Err() == nil {
+		buf := make([]byte, len(carry)+ChunkSize)
+		copy(buf, carry)
+		n, err := io.ReadFull(r, buf[len(carry):])
+		buf = buf[:len(carry)+n]
+
+		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !eof {
+			return err
+		}
+
+		if eof {
+			if len(buf) > 0 {
+				handle(buf)
+			}
+			return nil
+		}
+
+		cut := bytes.LastIndexByte(buf, '\n')
+		if cut == -1 {
+			// a line longer than the chunk, keep reading
+			carry = buf
+			continue
+		}
+		carry = append([]byte(nil), buf[cut+1:]...)
+		if !handle(buf[:cut+1]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseChunk parses the lines of c, taking the known fields from idx when
+// it is not nil, and indexes them when build is set
+func parseChunk(c chunk, source string, idx *index.Index, build bool) parsed {
+	data := c.data
+	result := parsed{entries: make([]model.LogEntry, 0, bytes.Count(data, []byte{'\n'})+1)}
+	if build {
+		result.index = index.NewChunk()
+	}
+
+	offset := c.offset
+	n := c.line
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		start := offset
+		offset += int64(len(line))
+		if data != nil {
+			offset++
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+
+		var e model.LogEntry
+		if idx != nil && n < idx.Lines() {
+			t, level, callID := idx.Known(n)
+			e = parser.ParseKnown(string(line), t, level, callID)
+		} else {
+			e = parser.ParseLogLine(string(line), 0)
+		}
+		e.Source = source
+		result.entries = append(result.entries, e)
+		if build {
+			result.index.Add(start, e)
+		}
+		n++
+	}
+	return result
+}
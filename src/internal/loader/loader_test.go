@@ -0,0 +1,221 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLog writes a log of n lines to a temporary file, with lines longer
+// than a chunk, CRLF line ends and no line break at the end
+func writeLog(t *testing.T, n int) (string, []string) {
+	t.Helper()
+	levels := []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+	var lines []string
+	for i := range n {
+		var line string
+		switch {
+		case i%97 == 0:
+			line = fmt.Sprintf("[2025-12-04 14:%02d:%02d] [INFO] %s", i/60%60, i%60, strings.Repeat("long ", ChunkSize/4))
+		case i%13 == 0:
+			line = fmt.Sprintf("continued line %d", i)
+		default:
+			line = fmt.Sprintf("[2025-12-04 14:%02d:%02d] [%s] Sent SIP: BYE sip:%d@h SIP/2.0\\r\\nCall-ID: c%d@h\\r\\n",
+				i/60%60, i%60, levels[i%len(levels)], i, i%50)
+		}
+		lines = append(lines, line)
+	}
+
+	var text strings.Builder
+	for i, line := range lines {
+		text.WriteString(line)
+		switch {
+		case i == len(lines)-1:
+		case i%7 == 0:
+			text.WriteString("\r\n")
+		default:
+			text.WriteString("\n")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(path, []byte(text.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, lines
+}
+
+// load loads the file at path and returns its entries and the last progress
+func load(t *testing.T, path string, opts Options) ([]model.LogEntry, Progress) {
+	t.Helper()
+	var entries []model.LogEntry
+	var last Progress
+	_, err := Load(context.Background(), path, opts, func(batch []model.LogEntry) {
+		entries = append(entries, batch...)
+	}, func(p Progress) {
+		last = p
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries, last
+}
+
+// compare checks entries against the lines parsed one by one
+func compare(t *testing.T, entries []model.LogEntry, lines []string, source string) {
+	t.Helper()
+	if len(entries) != len(lines) {
+		t.Fatalf("loaded %d entries, want %d", len(entries), len(lines))
+	}
+	for n, got := range entries {
+		want := parser.ParseLogLine(lines[n], 0)
+		want.Source = source
+		if !got.Time.Equal(want.Time) {
+			t.Fatalf("line %d: time %v, want %v", n, got.Time, want.Time)
+		}
+		got.Index, got.Time = want.Index, want.Time
+		if got != want {
+			t.Fatalf("line %d: got %+v\nwant %+v", n, got, want)
+		}
+	}
+}
+
+func setChunkSize(t *testing.T, size int) {
+	old := ChunkSize
+	ChunkSize = size
+	t.Cleanup(func() { ChunkSize = old })
+}
+
+func TestLoadChunks(t *testing.T) {
+	setChunkSize(t, 1024)
+	old := UseIndex
+	UseIndex = false
+	t.Cleanup(func() { UseIndex = old })
+
+	path, lines := writeLog(t, 2000)
+	entries, p := load(t, path, Options{})
+	compare(t, entries, lines, path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Done || p.Err != nil || p.Bytes != info.Size() || p.Lines != len(lines) || p.Indexed {
+		t.Errorf("final progress %+v", p)
+	}
+	if _, err := os.Stat(path + ".gfidx"); err == nil {
+		t.Error("index written without UseIndex")
+	}
+}
+
+func TestLoadCancel(t *testing.T) {
+	setChunkSize(t, 1024)
+	old := UseIndex
+	UseIndex = false
+	t.Cleanup(func() { UseIndex = old })
+
+	path, lines := writeLog(t, 2000)
+	ctx, cancel := context.WithCancel(context.Background())
+	var loaded int
+	_, err := Load(ctx, path, Options{}, func(batch []model.LogEntry) {
+		loaded += len(batch)
+		cancel()
+	}, nil)
+	if err != context.Canceled {
+		t.Errorf("Load = %v, want %v", err, context.Canceled)
+	}
+	if loaded == 0 || loaded == len(lines) {
+		t.Errorf("loaded %d of %d lines", loaded, len(lines))
+	}
+}
+
+func TestLoadIndex(t *testing.T) {
+	setChunkSize(t, 1024)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path, lines := writeLog(t, 2000)
+
+	// the first load parses the file and stores the sidecar
+	entries, p := load(t, path, Options{})
+	compare(t, entries, lines, path)
+	if p.Indexed {
+		t.Error("first load used an index")
+	}
+	stored, err := index.Open(path)
+	if err != nil {
+		t.Fatalf("sidecar not stored: %v", err)
+	}
+	if stored.Lines() != len(lines) {
+		t.Fatalf("index of %d lines, want %d", stored.Lines(), len(lines))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, n := range []int{0, 1, 7, 97, 1000, len(lines) - 1} {
+		e, err := stored.Entry(file, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Source = path
+		compare(t, []model.LogEntry{e}, lines[n:n+1], path)
+	}
+
+	// the second load takes the known fields from the sidecar
+	entries, p = load(t, path, Options{})
+	compare(t, entries, lines, path)
+	if !p.Indexed || p.Skipped != 0 {
+		t.Errorf("second load: indexed %v, skipped %d", p.Indexed, p.Skipped)
+	}
+
+	// only the lines fitting the budget are read
+	var skipped int
+	entries, p = load(t, path, Options{MaxEntries: 300, OnIndex: func(idx *index.Index, n int) {
+		skipped = n
+	}})
+	compare(t, entries, lines[len(lines)-300:], path)
+	if skipped != len(lines)-300 || p.Skipped != skipped {
+		t.Errorf("skipped %d and %d lines, want %d", skipped, p.Skipped, len(lines)-300)
+	}
+}
+
+func TestLoadStaleIndex(t *testing.T) {
+	setChunkSize(t, 1024)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path, lines := writeLog(t, 500)
+	load(t, path, Options{})
+
+	// a changed file makes the sidecar stale, it is rebuilt on the next load
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := "[2025-12-04 15:00:00] [ERROR] added later"
+	_, err = file.WriteString("\n" + added)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = append(lines, added)
+
+	if _, err := index.Open(path); !errors.Is(err, index.ErrStale) {
+		t.Fatalf("Open of a changed file = %v, want %v", err, index.ErrStale)
+	}
+	entries, p := load(t, path, Options{})
+	compare(t, entries, lines, path)
+	if p.Indexed {
+		t.Error("stale index used")
+	}
+	if idx, err := index.Open(path); err != nil || idx.Lines() != len(lines) {
+		t.Errorf("rebuilt index: %v", err)
+	}
+}
@@ -1,13 +1,32 @@
 package model
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
 
 type LogEntry struct {
 	Index           string
 	Timestamp       string
+	Time            time.Time
 	Level           string
 	LevelColor      tcell.Color
 	Message         string
 	OriginalMessage string
 	CallID          string
+	Source          string
+}
+
+// RawLine rebuilds the log line as it was read
+func (e LogEntry) RawLine() string {
+	if e.Timestamp == "" {
+		return e.OriginalMessage
+	}
+	return "[" + e.Timestamp + "] " + e.OriginalMessage
+}
+
+// Entries gives access to log entries by their position in the log.
+type Entries interface {
+	Get(pos int) (LogEntry, bool)
 }
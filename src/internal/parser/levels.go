@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type Level struct {
+	Name     string
+	Color    tcell.Color
+	Severity int
+}
+
+// Levels are checked in order, the first "[NAME]" found in a line wins.
+var Levels = []Level{
+	{"DEBUG", tcell.ColorDarkCyan, 10},
+	{"INFO", tcell.ColorGreen, 20},
+	{"WARN", tcell.ColorYellow, 30},
+	{"ERROR", tcell.ColorRed, 40},
+	{"WEB", tcell.ColorBlue, 20},
+}
+
+// RegisterLevel adds a custom level or replaces the one with the same name.
+func RegisterLevel(name string, color tcell.Color, severity int) {
+	for i, level := range Levels {
+		if level.Name == name {
+			Levels[i] = Level{name, color, severity}
+			return
+		}
+	}
+	Levels = append(Levels, Level{name, color, severity})
+}
+
+// AddLevels registers levels from a spec like "TRACE:5:gray,FATAL:50:purple".
+// The color is optional and defaults to white.
+func AddLevels(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return fmt.Errorf("invalid level %q, expected NAME:SEVERITY[:COLOR]", item)
+		}
+
+		severity, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid severity for level %s: %w", parts[0], err)
+		}
+
+		color := tcell.ColorWhite
+		if len(parts) == 3 {
+			color = tcell.GetColor(parts[2])
+			if color == tcell.ColorDefault {
+				return fmt.Errorf("unknown color %q for level %s", parts[2], parts[0])
+			}
+		}
+
+		RegisterLevel(strings.ToUpper(parts[0]), color, severity)
+	}
+	return nil
+}
+
+// Severity returns the severity of the named level, 0 for unknown levels.
+func Severity(name string) int {
+	for _, level := range Levels {
+		if level.Name == name {
+			return level.Severity
+		}
+	}
+	return 0
+}
+
+// Severities returns the distinct severities of all levels in ascending order.
+func Severities() []int {
+	seen := make(map[int]bool)
+	var result []int
+	for _, level := range Levels {
+		if !seen[level.Severity] {
+			seen[level.Severity] = true
+			result = append(result, level.Severity)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// SeverityName returns the name of the first level with the given severity.
+func SeverityName(severity int) string {
+	for _, level := range Levels {
+		if level.Severity == severity {
+			return level.Name
+		}
+	}
+	return strconv.Itoa(severity)
+}
+
+// LevelColor returns the color of the named level, white for unknown levels.
+func LevelColor(name string) tcell.Color {
+	for _, level := range Levels {
+		if level.Name == name {
+			return level.Color
+		}
+	}
+	return tcell.ColorWhite
+}
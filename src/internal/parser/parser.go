@@ -3,19 +3,24 @@ package parser
 import (
 	"fmt"
 	"gofly-cli/internal/model"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 )
 
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006/01/02 15:04:05",
+}
+
+var callIDRegexp = regexp.MustCompile(`(?i)call-id:[ \t]*([^\s\\]+)`)
+
 func ParseLogLine(line string, index int) model.LogEntry {
-	timestamp := ""
-	if len(line) > 20 && line[0] == '[' {
-		if idx := strings.Index(line, "]"); idx != -1 {
-			timestamp = line[1:idx]
-			line = strings.TrimSpace(line[idx+1:])
-		}
-	}
+	timestamp, line := splitTimestamp(line)
 
 	levelText, levelColor := levelColored(line)
 
@@ -24,36 +29,83 @@ func ParseLogLine(line string, index int) model.LogEntry {
 	return model.LogEntry{
 		Index:           fmt.Sprintf("%d", index),
 		Timestamp:       timestamp,
+		Time:            ParseTimestamp(timestamp),
 		Level:           levelText,
 		LevelColor:      levelColor,
 		Message:         messageText,
 		OriginalMessage: line,
+		CallID:          extractCallID(messageText),
+	}
+}
+
+// ParseKnown builds the entry of a line whose time, level and Call-ID are
+// already known, e.g. from an index, skipping the costly parts of ParseLogLine.
+func ParseKnown(line string, t time.Time, level, callID string) model.LogEntry {
+	timestamp, line := splitTimestamp(line)
+
+	messageText := strings.TrimSpace(line)
+	if level != "" {
+		messageText = decodeMessage(line)
+	}
+
+	return model.LogEntry{
+		Timestamp:       timestamp,
+		Time:            t,
+		Level:           level,
+		LevelColor:      LevelColor(level),
+		Message:         messageText,
+		OriginalMessage: line,
+		CallID:          callID,
+	}
+}
+
+// splitTimestamp cuts a leading "[timestamp]" off the line
+func splitTimestamp(line string) (string, string) {
+	if len(line) > 20 && line[0] == '[' {
+		if idx := strings.Index(line, "]"); idx != -1 {
+			return line[1:idx], strings.TrimSpace(line[idx+1:])
+		}
+	}
+	return "", line
+}
+
+// ParseTimestamp parses a log timestamp in local time, fractional seconds
+// are accepted by every layout. Unknown formats give the zero time.
+func ParseTimestamp(timestamp string) time.Time {
+	if timestamp == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, timestamp, time.Local); err == nil {
+			return t
+		}
 	}
+
+	return time.Time{}
 }
 
 func levelColored(line string) (string, tcell.Color) {
-	switch {
-	case strings.Contains(line, "[DEBUG]"):
-		return "DEBUG", tcell.ColorDarkCyan
-	case strings.Contains(line, "[INFO]"):
-		return "INFO", tcell.ColorGreen
-	case strings.Contains(line, "[WARN]"):
-		return "WARN", tcell.ColorYellow
-	case strings.Contains(line, "[ERROR]"):
-		return "ERROR", tcell.ColorRed
-	case strings.Contains(line, "[WEB]"):
-		return "WEB", tcell.ColorBlue
-	default:
-		return "", tcell.ColorWhite
+	for _, level := range Levels {
+		if strings.Contains(line, "["+level.Name+"]") {
+			return level.Name, level.Color
+		}
 	}
+	return "", tcell.ColorWhite
 }
 
 func decodeMessage(line string) string {
-	line = strings.ReplaceAll(line, "[DEBUG]", "")
-	line = strings.ReplaceAll(line, "[INFO]", "")
-	line = strings.ReplaceAll(line, "[WARN]", "")
-	line = strings.ReplaceAll(line, "[ERROR]", "")
-	line = strings.ReplaceAll(line, "[WEB]", "")
+	for _, level := range Levels {
+		line = strings.ReplaceAll(line, "["+level.Name+"]", "")
+	}
 
 	return strings.TrimSpace(line)
 }
+
+func extractCallID(message string) string {
+	if m := callIDRegexp.FindStringSubmatch(message); m != nil {
+		return m[1]
+	}
+
+	return ""
+}
@@ -0,0 +1,129 @@
+package pcap
+
+import (
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/sip"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultSIPPort = 5060
+
+type packet struct {
+	pos      int
+	arrow    calls.Arrow
+	src, dst string
+}
+
+// WriteSIP writes the SIP messages found in the entries at positions of logs
+// as UDP packets. Addresses come from the ladder of each call (Via and
+// Request-URI), then the Contact header and the source of the entry; host
+// names get stable synthetic addresses. It returns the number of packets.
+func WriteSIP(w io.Writer, logs model.Entries, positions []int) (int, error) {
+	byCall := make(map[string][]int)
+	var order []string
+	for _, pos := range positions {
+		e, ok := logs.Get(pos)
+		if !ok || e.CallID == "" {
+			continue
+		}
+		id := e.CallID
+		if _, ok := byCall[id]; !ok {
+			order = append(order, id)
+		}
+		byCall[id] = append(byCall[id], pos)
+	}
+
+	var packets []packet
+	for _, id := range order {
+		ladder := calls.BuildLadder(logs, byCall[id])
+		for _, arrow := range ladder.Arrows {
+			packets = append(packets, packet{
+				pos:   arrow.Pos,
+				arrow: arrow,
+				src:   ladder.Participants[arrow.From],
+				dst:   ladder.Participants[arrow.To],
+			})
+		}
+	}
+	sort.SliceStable(packets, func(i, j int) bool { return packets[i].pos < packets[j].pos })
+
+	writer, err := NewWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	r := &resolver{synthetic: make(map[string]netip.Addr)}
+	// entries without a parsed timestamp keep the time of the previous
+	// packet, those before the first timestamp get the first one
+	ts := time.Unix(0, 0)
+	for _, p := range packets {
+		if e, _ := logs.Get(p.pos); !e.Time.IsZero() {
+			ts = e.Time
+			break
+		}
+	}
+	for _, p := range packets {
+		e, _ := logs.Get(p.pos)
+		if !e.Time.IsZero() {
+			ts = e.Time
+		}
+
+		src := r.resolve(p.src, p.arrow.Message, e)
+		dst := r.resolve(p.dst, p.arrow.Message, e)
+		if src.Addr().Is4() != dst.Addr().Is4() {
+			src = netip.AddrPortFrom(netip.AddrFrom16(src.Addr().As16()), src.Port())
+			dst = netip.AddrPortFrom(netip.AddrFrom16(dst.Addr().As16()), dst.Port())
+		}
+
+		if err := writer.WriteUDP(ts, src, dst, p.arrow.Message.Bytes()); err != nil {
+			return 0, fmt.Errorf("write packet of entry %d: %w", p.pos, err)
+		}
+	}
+
+	return len(packets), nil
+}
+
+type resolver struct {
+	synthetic map[string]netip.Addr
+}
+
+func (r *resolver) resolve(addr string, msg *sip.Message, e model.LogEntry) netip.AddrPort {
+	if addr == "?" {
+		addr = sip.URIHost(msg.Header("Contact"))
+	}
+	if addr == "" {
+		addr = e.Source
+	}
+
+	host, port := splitHostPort(addr)
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return netip.AddrPortFrom(ip.Unmap(), port)
+	}
+
+	ip, ok := r.synthetic[host]
+	if !ok {
+		n := len(r.synthetic) + 1
+		ip = netip.AddrFrom4([4]byte{10, 255, byte(n >> 8), byte(n)})
+		r.synthetic[host] = ip
+	}
+	return netip.AddrPortFrom(ip, port)
+}
+
+func splitHostPort(addr string) (string, uint16) {
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, defaultSIPPort
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return host, defaultSIPPort
+	}
+	return host, uint16(port)
+}
@@ -0,0 +1,162 @@
+// Package pcap writes libpcap capture files with synthetic UDP packets.
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+)
+
+// MaxPayload is the largest UDP payload that fits the IPv4 length field.
+const MaxPayload = 65535 - 20 - 8
+
+var ErrPayloadTooLarge = errors.New("payload too large for a UDP datagram")
+
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	// room for the largest datagram with its Ethernet and IP headers
+	snapLen          = 262144
+	linkTypeEthernet = 1
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	protocolUDP   = 17
+)
+
+// Writer writes packets to a libpcap file with Ethernet link type.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the file header to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], magicMicroseconds)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], snapLen)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeEthernet)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// WriteUDP writes payload as a UDP datagram from src to dst. Both addresses
+// must be of the same family. Payloads above MaxPayload are rejected with
+// ErrPayloadTooLarge.
+func (w *Writer) WriteUDP(ts time.Time, src, dst netip.AddrPort, payload []byte) error {
+	if len(payload) > MaxPayload {
+		return fmt.Errorf("%w: %d bytes", ErrPayloadTooLarge, len(payload))
+	}
+
+	udp := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:], src.Port())
+	binary.BigEndian.PutUint16(udp[2:], dst.Port())
+	binary.BigEndian.PutUint16(udp[4:], uint16(len(udp)))
+	copy(udp[8:], payload)
+
+	var ip []byte
+	etherType := uint16(etherTypeIPv4)
+	if src.Addr().Is4() {
+		ip = ipv4Header(src.Addr(), dst.Addr(), len(udp))
+	} else {
+		etherType = etherTypeIPv6
+		ip = ipv6Header(src.Addr(), dst.Addr(), len(udp))
+	}
+	binary.BigEndian.PutUint16(udp[6:], udpChecksum(src.Addr(), dst.Addr(), udp))
+
+	// locally administered MAC addresses derived from the IP addresses
+	eth := make([]byte, 14)
+	copy(eth[0:6], macOf(dst.Addr()))
+	copy(eth[6:12], macOf(src.Addr()))
+	binary.BigEndian.PutUint16(eth[12:], etherType)
+
+	frame := make([]byte, 0, len(eth)+len(ip)+len(udp))
+	frame = append(frame, eth...)
+	frame = append(frame, ip...)
+	frame = append(frame, udp...)
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[0:], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(frame)))
+
+	if _, err := w.w.Write(record); err != nil {
+		return err
+	}
+	_, err := w.w.Write(frame)
+	return err
+}
+
+func ipv4Header(src, dst netip.Addr, payloadLen int) []byte {
+	h := make([]byte, 20)
+	h[0] = 0x45
+	binary.BigEndian.PutUint16(h[2:], uint16(20+payloadLen))
+	h[8] = 64
+	h[9] = protocolUDP
+	s, d := src.As4(), dst.As4()
+	copy(h[12:16], s[:])
+	copy(h[16:20], d[:])
+	binary.BigEndian.PutUint16(h[10:], ^sum(h, 0))
+	return h
+}
+
+func ipv6Header(src, dst netip.Addr, payloadLen int) []byte {
+	h := make([]byte, 40)
+	h[0] = 0x60
+	binary.BigEndian.PutUint16(h[4:], uint16(payloadLen))
+	h[6] = protocolUDP
+	h[7] = 64
+	s, d := src.As16(), dst.As16()
+	copy(h[8:24], s[:])
+	copy(h[24:40], d[:])
+	return h
+}
+
+func udpChecksum(src, dst netip.Addr, udp []byte) uint16 {
+	var pseudo []byte
+	if src.Is4() {
+		s, d := src.As4(), dst.As4()
+		pseudo = append(pseudo, s[:]...)
+		pseudo = append(pseudo, d[:]...)
+		pseudo = append(pseudo, 0, protocolUDP, byte(len(udp)>>8), byte(len(udp)))
+	} else {
+		s, d := src.As16(), dst.As16()
+		pseudo = append(pseudo, s[:]...)
+		pseudo = append(pseudo, d[:]...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(udp)))
+		pseudo = append(pseudo, 0, 0, 0, protocolUDP)
+	}
+
+	checksum := ^sum(udp, sum(pseudo, 0))
+	if checksum == 0 {
+		return 0xffff
+	}
+	return checksum
+}
+
+// sum is the ones' complement sum of data used by IP and UDP checksums
+func sum(data []byte, initial uint16) uint16 {
+	total := uint32(initial)
+	for i := 0; i+1 < len(data); i += 2 {
+		total += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		total += uint32(data[len(data)-1]) << 8
+	}
+	for total > 0xffff {
+		total = total>>16 + total&0xffff
+	}
+	return uint16(total)
+}
+
+func macOf(addr netip.Addr) []byte {
+	b := addr.As16()
+	return []byte{0x02, 0x00, b[12], b[13], b[14], b[15]}
+}
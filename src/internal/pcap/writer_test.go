@@ -0,0 +1,75 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"gofly-cli/internal/model"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+type entrySlice []model.LogEntry
+
+func (s entrySlice) Get(pos int) (model.LogEntry, bool) {
+	if pos < 0 || pos >= len(s) {
+		return model.LogEntry{}, false
+	}
+	return s[pos], true
+}
+
+func TestWriteUDPPayloadSize(t *testing.T) {
+	src := netip.MustParseAddrPort("10.0.0.1:5060")
+	dst := netip.MustParseAddrPort("10.0.0.2:5060")
+
+	tests := []struct {
+		size int
+		err  error
+	}{
+		{0, nil},
+		{MaxPayload, nil},
+		{MaxPayload + 1, ErrPayloadTooLarge},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.WriteUDP(time.Unix(1, 0), src, dst, make([]byte, tt.size))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("size %d: err = %v, want %v", tt.size, err, tt.err)
+		}
+		if tt.err == nil {
+			// file header, record header, Ethernet, IPv4 and UDP headers
+			if want := 24 + 16 + 14 + 20 + 8 + tt.size; buf.Len() != want {
+				t.Errorf("size %d: wrote %d bytes, want %d", tt.size, buf.Len(), want)
+			}
+		}
+	}
+}
+
+func TestWriteSIPTimestamps(t *testing.T) {
+	invite := `INVITE sip:2@10.0.0.2 SIP/2.0\r\nVia: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1\r\nFrom: <sip:1@10.0.0.1>;tag=1\r\nTo: <sip:2@10.0.0.2>\r\nCall-ID: c1\r\nCSeq: 1 INVITE\r\n`
+	ok := `SIP/2.0 200 OK\r\nVia: SIP/2.0/UDP 10.0.0.1:5060;branch=z9hG4bK1\r\nFrom: <sip:1@10.0.0.1>;tag=1\r\nTo: <sip:2@10.0.0.2>;tag=2\r\nCall-ID: c1\r\nCSeq: 1 INVITE\r\n`
+	first := time.Date(2025, 12, 4, 14, 30, 0, 0, time.UTC)
+	logs := entrySlice{
+		{CallID: "c1", Message: "Sent SIP: " + invite},
+		{CallID: "c1", Message: "Received SIP: " + ok, Time: first},
+	}
+
+	var buf bytes.Buffer
+	n, err := WriteSIP(&buf, logs, []int{0, 1})
+	if err != nil || n != 2 {
+		t.Fatalf("WriteSIP = %d, %v", n, err)
+	}
+
+	data := buf.Bytes()[24:]
+	for i := range n {
+		if sec := binary.LittleEndian.Uint32(data); int64(sec) != first.Unix() {
+			t.Errorf("packet %d: timestamp %d, want %d", i, sec, first.Unix())
+		}
+		data = data[16+binary.LittleEndian.Uint32(data[8:]):]
+	}
+}
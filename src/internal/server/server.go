@@ -0,0 +1,124 @@
+// Package server speaks the server side of the gofly UDP protocol: clients
+// subscribe with SUB, get SUB_ACK back and receive log lines as datagrams
+// while they keep renewing the subscription.
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientTTL is how long a subscription lasts without a new SUB.
+var ClientTTL = 10 * time.Second
+
+type Server struct {
+	conn *net.UDPConn
+
+	mu      sync.Mutex
+	clients map[string]client
+	// closed when the first client subscribes
+	subscribed chan struct{}
+}
+
+type client struct {
+	addr *net.UDPAddr
+	seen time.Time
+}
+
+// Listen opens the UDP socket at addr, e.g. "127.0.0.1:9090".
+func Listen(addr string) (*Server, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		conn:       conn,
+		clients:    make(map[string]client),
+		subscribed: make(chan struct{}),
+	}, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Run answers subscriptions until ctx is cancelled, then closes the socket.
+func (s *Server) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		s.conn.Close()
+	}()
+
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(string(buf[:n])), "SUB") {
+			s.subscribe(addr)
+			s.conn.WriteToUDP([]byte("SUB_ACK"), addr)
+		}
+	}
+}
+
+func (s *Server) subscribe(addr *net.UDPAddr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.subscribed:
+	default:
+		close(s.subscribed)
+	}
+	s.clients[addr.String()] = client{addr: addr, seen: time.Now()}
+}
+
+// Subscribed is closed once the first client subscribed.
+func (s *Server) Subscribed() <-chan struct{} {
+	return s.subscribed
+}
+
+// Clients returns the number of live subscriptions.
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	return len(s.clients)
+}
+
+// Send sends line to every subscribed client.
+func (s *Server) Send(line string) {
+	s.mu.Lock()
+	s.expire()
+	addrs := make([]*net.UDPAddr, 0, len(s.clients))
+	for _, c := range s.clients {
+		addrs = append(addrs, c.addr)
+	}
+	s.mu.Unlock()
+
+	for _, addr := range addrs {
+		s.conn.WriteToUDP([]byte(line), addr)
+	}
+}
+
+// expire drops clients that stopped subscribing, s.mu must be held
+func (s *Server) expire() {
+	for key, c := range s.clients {
+		if time.Since(c.seen) > ClientTTL {
+			delete(s.clients, key)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package sip
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Message is a SIP request or response found in a log message.
+type Message struct {
+	IsRequest  bool
+	Method     string
+	RequestURI string
+	StatusCode int
+	Reason     string
+	Headers    []Header
+	Body       string
+}
+
+type Header struct {
+	Name  string
+	Value string
+}
+
+var (
+	startLineRegexp = regexp.MustCompile(
+		`(?:^|\s)((?:INVITE|ACK|BYE|CANCEL|OPTIONS|REGISTER|PRACK|SUBSCRIBE|NOTIFY|PUBLISH|INFO|REFER|MESSAGE|UPDATE) \S+ SIP/2\.0|SIP/2\.0 \d{3}(?: [^\n]*)?)`)
+
+	// used to split messages logged on a single line into header lines
+	headerStartRegexp = regexp.MustCompile(
+		`\s((?i:Via|From|To|Call-ID|CSeq|Contact|Max-Forwards|Content-Type|Content-Length|User-Agent|Server|Allow|Supported|Require|Record-Route|Route|Expires|Session-Expires|Min-SE|Authorization|WWW-Authenticate|Proxy-Authenticate|Proxy-Authorization|P-Asserted-Identity|P-Preferred-Identity|Remote-Party-ID|Diversion|Reason|Privacy|Accept|Event|Subscription-State|Refer-To|Referred-By|X-[A-Za-z0-9-]+):)`)
+
+	sdpStartRegexp = regexp.MustCompile(`\sv=0\s`)
+	sdpLineRegexp  = regexp.MustCompile(`\s([a-z]=)`)
+)
+
+var compactForms = map[string]string{
+	"v": "Via",
+	"f": "From",
+	"t": "To",
+	"i": "Call-ID",
+	"m": "Contact",
+	"l": "Content-Length",
+	"c": "Content-Type",
+	"k": "Supported",
+	"s": "Subject",
+	"e": "Content-Encoding",
+}
+
+// Parse finds a SIP message in a log message. Line breaks may be real,
+// escaped as "\r\n" or missing completely when the message was logged
+// on a single line.
+func Parse(text string) (*Message, bool) {
+	if !strings.Contains(text, "SIP/2.0") {
+		return nil, false
+	}
+
+	text = strings.ReplaceAll(text, `\r\n`, "\n")
+	text = strings.ReplaceAll(text, `\n`, "\n")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	loc := startLineRegexp.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil, false
+	}
+
+	text = text[loc[2]:]
+	startLine, rest, _ := strings.Cut(text, "\n")
+
+	if !strings.Contains(rest, "\n") {
+		rest = splitSingleLine(startLine + rest)
+		startLine, rest, _ = strings.Cut(rest, "\n")
+	}
+
+	msg := &Message{}
+	if !msg.parseStartLine(strings.TrimSpace(startLine)) {
+		return nil, false
+	}
+
+	head, body, _ := strings.Cut(rest, "\n\n")
+	for _, line := range strings.Split(head, "\n") {
+		// a line starting with white space continues the previous header
+		if line != "" && (line[0] == ' ' || line[0] == '\t') && len(msg.Headers) > 0 {
+			h := &msg.Headers[len(msg.Headers)-1]
+			h.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		msg.Headers = append(msg.Headers, Header{
+			Name:  canonicalName(strings.TrimSpace(name)),
+			Value: strings.TrimSpace(value),
+		})
+	}
+	msg.Body = strings.TrimSpace(body)
+
+	if !msg.IsRequest {
+		_, msg.Method = msg.CSeq()
+	}
+
+	return msg, true
+}
+
+// ParseLines parses a message spread over the texts of consecutive log
+// entries, e.g. when every line of the message was logged on its own. The
+// texts after the first are only used when the first holds no header.
+func ParseLines(lines []string) (*Message, bool) {
+	if len(lines) == 0 {
+		return nil, false
+	}
+	if msg, ok := Parse(lines[0]); ok && len(msg.Headers) > 0 || len(lines) == 1 {
+		return msg, ok
+	}
+	return Parse(strings.Join(lines, "\n"))
+}
+
+func splitSingleLine(text string) string {
+	if idx := sdpStartRegexp.FindStringIndex(text); idx != nil {
+		head := text[:idx[0]]
+		body := sdpLineRegexp.ReplaceAllString(text[idx[0]:], "\n$1")
+		return headerStartRegexp.ReplaceAllString(head, "\n$1") + "\n\n" + body
+	}
+	return headerStartRegexp.ReplaceAllString(text, "\n$1")
+}
+
+func (m *Message) parseStartLine(line string) bool {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	if parts[0] == "SIP/2.0" {
+		code, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return false
+		}
+		m.StatusCode = code
+		if len(parts) == 3 {
+			m.Reason = parts[2]
+		}
+		return true
+	}
+
+	if len(parts) != 3 {
+		return false
+	}
+	m.IsRequest = true
+	m.Method = parts[0]
+	m.RequestURI = parts[1]
+	return true
+}
+
+func canonicalName(name string) string {
+	if full, ok := compactForms[strings.ToLower(name)]; ok {
+		return full
+	}
+	return name
+}
+
+func (m *Message) StartLine() string {
+	if m.IsRequest {
+		return m.Method + " " + m.RequestURI + " SIP/2.0"
+	}
+	return strings.TrimSpace("SIP/2.0 " + strconv.Itoa(m.StatusCode) + " " + m.Reason)
+}
+
+// Header returns the first value of the named header, case-insensitive.
+func (m *Message) Header(name string) string {
+	for _, h := range m.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// HeaderValues returns all values of the named header, case-insensitive.
+func (m *Message) HeaderValues(name string) []string {
+	var values []string
+	for _, h := range m.Headers {
+		if strings.EqualFold(h.Name, name) {
+			values = append(values, h.Value)
+		}
+	}
+	return values
+}
+
+func (m *Message) CallID() string {
+	return m.Header("Call-ID")
+}
+
+// CSeq returns the sequence number and method of the CSeq header.
+func (m *Message) CSeq() (int, string) {
+	num, method, _ := strings.Cut(m.Header("CSeq"), " ")
+	n, _ := strconv.Atoi(strings.TrimSpace(num))
+	return n, strings.TrimSpace(method)
+}
+
+// Bytes rebuilds the message as sent on the wire: CRLF line endings
+// and a Content-Length matching the body.
+func (m *Message) Bytes() []byte {
+	var b strings.Builder
+
+	body := ""
+	if m.Body != "" {
+		body = strings.ReplaceAll(m.Body, "\n", "\r\n") + "\r\n"
+	}
+
+	b.WriteString(m.StartLine())
+	b.WriteString("\r\n")
+	hasLength := false
+	for _, h := range m.Headers {
+		value := h.Value
+		if strings.EqualFold(h.Name, "Content-Length") {
+			value = strconv.Itoa(len(body))
+			hasLength = true
+		}
+		b.WriteString(h.Name + ": " + value + "\r\n")
+	}
+	if !hasLength {
+		b.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n")
+	}
+	b.WriteString("\r\n")
+	b.WriteString(body)
+
+	return []byte(b.String())
+}
+
+// IsFinal reports whether m is a final (non 1xx) response.
+func (m *Message) IsFinal() bool {
+	return !m.IsRequest && m.StatusCode >= 200
+}
+
+// TopVia returns sent-by (host[:port]) and the branch of the first Via header.
+func (m *Message) TopVia() (string, string) {
+	via := m.Header("Via")
+	if via == "" {
+		return "", ""
+	}
+	// several Via values may be folded into one header
+	via, _, _ = strings.Cut(via, ",")
+
+	fields := strings.Fields(via)
+	if len(fields) < 2 {
+		return "", ""
+	}
+
+	sentBy, params, _ := strings.Cut(fields[1], ";")
+	if len(fields) > 2 {
+		params += ";" + strings.Join(fields[2:], "")
+	}
+
+	branch := ""
+	for _, param := range strings.Split(params, ";") {
+		if value, ok := strings.CutPrefix(param, "branch="); ok {
+			branch = value
+		}
+	}
+
+	return sentBy, branch
+}
+
+// Tag returns the tag parameter of a From or To header value.
+func Tag(value string) string {
+	for _, param := range strings.Split(value, ";") {
+		if tag, ok := strings.CutPrefix(strings.TrimSpace(param), "tag="); ok {
+			return tag
+		}
+	}
+	return ""
+}
+
+// URIUser returns the user part of the SIP URI in a header value or Request-URI,
+// e.g. "79001234567" for `"Bob" <sip:79001234567@10.0.0.1>;tag=1`.
+func URIUser(value string) string {
+	uri := uriOf(value)
+	user, _, found := strings.Cut(uri, "@")
+	if !found {
+		return ""
+	}
+	user, _, _ = strings.Cut(user, ";")
+	return user
+}
+
+// URIHost returns host[:port] of the SIP URI in a header value or Request-URI.
+func URIHost(value string) string {
+	uri := uriOf(value)
+	if _, host, found := strings.Cut(uri, "@"); found {
+		uri = host
+	}
+	uri, _, _ = strings.Cut(uri, ";")
+	uri, _, _ = strings.Cut(uri, "?")
+	return uri
+}
+
+func uriOf(value string) string {
+	if start := strings.Index(value, "<"); start != -1 {
+		if end := strings.Index(value[start:], ">"); end != -1 {
+			value = value[start+1 : start+end]
+		}
+	} else {
+		value, _, _ = strings.Cut(strings.TrimSpace(value), ";")
+	}
+
+	for _, scheme := range []string{"sips:", "sip:", "tel:"} {
+		if rest, ok := strings.CutPrefix(value, scheme); ok {
+			return rest
+		}
+	}
+	return value
+}
@@ -0,0 +1,242 @@
+package sip
+
+import (
+	"strings"
+	"testing"
+)
+
+// headers writes the headers of msg as "Name=value|Name=value"
+func headers(msg *Message) string {
+	parts := make([]string, len(msg.Headers))
+	for i, h := range msg.Headers {
+		parts[i] = h.Name + "=" + h.Value
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		start   string
+		headers string
+		body    string
+		method  string
+	}{
+		{
+			name:    "escaped line breaks",
+			text:    `Received SIP: INVITE sip:1@10.0.0.9 SIP/2.0\r\nCall-ID: a1\r\nCSeq: 1 INVITE\r\n\r\nv=0\r\ns=-`,
+			start:   "INVITE sip:1@10.0.0.9 SIP/2.0",
+			headers: "Call-ID=a1|CSeq=1 INVITE",
+			body:    "v=0\ns=-",
+			method:  "INVITE",
+		},
+		{
+			name:    "real line breaks",
+			text:    "SIP/2.0 180 Ringing\r\nCall-ID: a1\r\nCSeq: 1 INVITE\r\n",
+			start:   "SIP/2.0 180 Ringing",
+			headers: "Call-ID=a1|CSeq=1 INVITE",
+			method:  "INVITE",
+		},
+		{
+			name: "folded headers",
+			text: "INVITE sip:1@h SIP/2.0\nVia: SIP/2.0/UDP a:5060;branch=z1,\n SIP/2.0/UDP b:5060\n" +
+				"Subject: long\n\tsubject\nCall-ID: a1\n",
+			start:   "INVITE sip:1@h SIP/2.0",
+			headers: "Via=SIP/2.0/UDP a:5060;branch=z1, SIP/2.0/UDP b:5060|Subject=long subject|Call-ID=a1",
+			method:  "INVITE",
+		},
+		{
+			name:    "escaped folded header",
+			text:    `BYE sip:1@h SIP/2.0\r\nVia: SIP/2.0/UDP a\r\n  ;branch=z2\r\nCall-ID: a1\r\n`,
+			start:   "BYE sip:1@h SIP/2.0",
+			headers: "Via=SIP/2.0/UDP a ;branch=z2|Call-ID=a1",
+			method:  "BYE",
+		},
+		{
+			name:    "compact forms",
+			text:    "ACK sip:1@h SIP/2.0\nv: SIP/2.0/UDP a\nf: <sip:2@h>;tag=1\nt: <sip:1@h>\ni: a1\nm: <sip:2@a>\nl: 0\nc: text/plain\nk: timer\n",
+			start:   "ACK sip:1@h SIP/2.0",
+			headers: "Via=SIP/2.0/UDP a|From=<sip:2@h>;tag=1|To=<sip:1@h>|Call-ID=a1|Contact=<sip:2@a>|Content-Length=0|Content-Type=text/plain|Supported=timer",
+			method:  "ACK",
+		},
+		{
+			name:    "single line",
+			text:    "Sent: SIP/2.0 200 OK Via: SIP/2.0/UDP a;branch=z3 call-id: a1 CSeq: 2 BYE Content-Length: 0",
+			start:   "SIP/2.0 200 OK",
+			headers: "Via=SIP/2.0/UDP a;branch=z3|call-id=a1|CSeq=2 BYE|Content-Length=0",
+			method:  "BYE",
+		},
+		{
+			name:    "single line with a body",
+			text:    "INVITE sip:1@h SIP/2.0 Call-ID: a1 Content-Type: application/sdp v=0 o=- 1 1 IN IP4 a c=IN IP4 a",
+			start:   "INVITE sip:1@h SIP/2.0",
+			headers: "Call-ID=a1|Content-Type=application/sdp",
+			body:    "v=0\no=- 1 1 IN IP4 a\nc=IN IP4 a",
+			method:  "INVITE",
+		},
+		{
+			name:    "no body and no Content-Length",
+			text:    `OPTIONS sip:h SIP/2.0\r\nCall-ID: a1\r\nCSeq: 7 OPTIONS`,
+			start:   "OPTIONS sip:h SIP/2.0",
+			headers: "Call-ID=a1|CSeq=7 OPTIONS",
+			method:  "OPTIONS",
+		},
+		{
+			name:   "start line only",
+			text:   "SIP/2.0 486",
+			start:  "SIP/2.0 486",
+			method: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := Parse(tt.text)
+			if !ok {
+				t.Fatal("no message found")
+			}
+			if msg.StartLine() != tt.start {
+				t.Errorf("start line = %q, want %q", msg.StartLine(), tt.start)
+			}
+			if got := headers(msg); got != tt.headers {
+				t.Errorf("headers = %q, want %q", got, tt.headers)
+			}
+			if msg.Body != tt.body {
+				t.Errorf("body = %q, want %q", msg.Body, tt.body)
+			}
+			if msg.Method != tt.method {
+				t.Errorf("method = %q, want %q", msg.Method, tt.method)
+			}
+		})
+	}
+}
+
+func TestParseNoMessage(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"OPTIONS keepalive to 10.0.0.1",
+		"upgraded to SIP/2.0 stack",
+		"SIP/2.0 abc Ringing",
+		"INVITE SIP/2.0",
+	} {
+		if msg, ok := Parse(text); ok {
+			t.Errorf("Parse(%q) = %q", text, msg.StartLine())
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		start   string
+		headers string
+		body    string
+	}{
+		{
+			name: "one entry per line",
+			lines: []string{"Received SIP:", "INVITE sip:1@h SIP/2.0", "Via: SIP/2.0/UDP a", " ;branch=z4",
+				"Call-ID: a1", "Content-Length: 3", "", "v=0"},
+			start:   "INVITE sip:1@h SIP/2.0",
+			headers: "Via=SIP/2.0/UDP a ;branch=z4|Call-ID=a1|Content-Length=3",
+			body:    "v=0",
+		},
+		{
+			name:    "start line entry",
+			lines:   []string{"SIP/2.0 200 OK", "Call-ID: a1", "CSeq: 1 INVITE"},
+			start:   "SIP/2.0 200 OK",
+			headers: "Call-ID=a1|CSeq=1 INVITE",
+		},
+		{
+			name:    "whole message in the first entry",
+			lines:   []string{`BYE sip:1@h SIP/2.0\r\nCall-ID: a1\r\n`, "unrelated: line"},
+			start:   "BYE sip:1@h SIP/2.0",
+			headers: "Call-ID=a1",
+		},
+		{
+			name:  "single entry",
+			lines: []string{"BYE sip:1@h SIP/2.0"},
+			start: "BYE sip:1@h SIP/2.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := ParseLines(tt.lines)
+			if !ok {
+				t.Fatal("no message found")
+			}
+			if msg.StartLine() != tt.start {
+				t.Errorf("start line = %q, want %q", msg.StartLine(), tt.start)
+			}
+			if got := headers(msg); got != tt.headers {
+				t.Errorf("headers = %q, want %q", got, tt.headers)
+			}
+			if msg.Body != tt.body {
+				t.Errorf("body = %q, want %q", msg.Body, tt.body)
+			}
+		})
+	}
+
+	if _, ok := ParseLines(nil); ok {
+		t.Error("message found without lines")
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "no body and no Content-Length",
+			text: "BYE sip:1@h SIP/2.0\nCall-ID: a1\n",
+			want: "BYE sip:1@h SIP/2.0\r\nCall-ID: a1\r\nContent-Length: 0\r\n\r\n",
+		},
+		{
+			name: "Content-Length of a missing body",
+			text: "SIP/2.0 200 OK\nCall-ID: a1\nContent-Length: 120\n",
+			want: "SIP/2.0 200 OK\r\nCall-ID: a1\r\nContent-Length: 0\r\n\r\n",
+		},
+		{
+			name: "body",
+			text: "INVITE sip:1@h SIP/2.0\ni: a1\nl: 1\n\nv=0\ns=-",
+			want: "INVITE sip:1@h SIP/2.0\r\nCall-ID: a1\r\nContent-Length: 10\r\n\r\nv=0\r\ns=-\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := Parse(tt.text)
+			if !ok {
+				t.Fatal("no message found")
+			}
+			if got := string(msg.Bytes()); got != tt.want {
+				t.Errorf("Bytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderValues(t *testing.T) {
+	msg, ok := Parse("INVITE sip:1@h SIP/2.0\nVia: SIP/2.0/UDP b:5070 ;branch=z5\nvia: SIP/2.0/UDP c\n" +
+		"From: \"A\" <sip:2@h>;tag=f1\nTo: <sip:1@h>\n")
+	if !ok {
+		t.Fatal("no message found")
+	}
+
+	if got := msg.HeaderValues("VIA"); len(got) != 2 {
+		t.Errorf("Via values = %q", got)
+	}
+	if sentBy, branch := msg.TopVia(); sentBy != "b:5070" || branch != "z5" {
+		t.Errorf("top Via = %q, %q", sentBy, branch)
+	}
+	if tag := Tag(msg.Header("from")); tag != "f1" {
+		t.Errorf("From tag = %q", tag)
+	}
+	if tag := Tag(msg.Header("To")); tag != "" {
+		t.Errorf("To tag = %q", tag)
+	}
+	if user, host := URIUser(msg.Header("From")), URIHost(msg.RequestURI); user != "2" || host != "h" {
+		t.Errorf("From user %q, Request-URI host %q", user, host)
+	}
+}
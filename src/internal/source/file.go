@@ -0,0 +1,57 @@
+package source
+
+import (
+	"context"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/loader"
+	"gofly-cli/internal/model"
+	"sync"
+	"time"
+)
+
+// File loads a log file once, see loader.Load.
+type File struct {
+	Path    string
+	Options loader.Options
+
+	mu    sync.Mutex
+	index *index.Index
+}
+
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+func (f *File) Name() string {
+	return f.Path
+}
+
+// Run reports the progress of the load in status events, the last one has
+// Done set, also when the file can't be opened.
+func (f *File) Run(ctx context.Context, emit func([]model.LogEntry), status func(Status)) error {
+	done := false
+	idx, err := loader.Load(ctx, f.Path, f.Options, emit, func(p loader.Progress) {
+		done = p.Done
+		status(Status{Source: f.Path, Time: time.Now(), Progress: &p})
+	})
+
+	if !done {
+		p := loader.Progress{Done: true, Err: err}
+		status(Status{Source: f.Path, Time: time.Now(), Progress: &p})
+	}
+
+	if err == nil {
+		f.mu.Lock()
+		f.index = idx
+		f.mu.Unlock()
+	}
+	return err
+}
+
+// Index returns the sidecar index of a completely loaded file, nil before
+// or when there is none.
+func (f *File) Index() *index.Index {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.index
+}
@@ -0,0 +1,65 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxBatch bounds the lines of a stream emitted at once
+const maxBatch = 1000
+
+// Reader reads log lines from a stream like stdin until it ends.
+type Reader struct {
+	name string
+	r    io.Reader
+}
+
+func NewReader(name string, r io.Reader) *Reader {
+	return &Reader{name: name, r: r}
+}
+
+// Stdin reads the standard input, e.g. a log piped into gofly-cli.
+func Stdin() *Reader {
+	return NewReader("stdin", os.Stdin)
+}
+
+func (r *Reader) Name() string {
+	return r.name
+}
+
+// Run emits lines in batches as soon as no more input is buffered, so a
+// followed log shows up line by line. A blocked read only notices the
+// cancellation of ctx when the next line arrives.
+func (r *Reader) Run(ctx context.Context, emit func([]model.LogEntry), status func(Status)) error {
+	in := bufio.NewReaderSize(r.r, 64<<10)
+	var batch []model.LogEntry
+
+	for ctx.Err() == nil {
+		line, err := in.ReadString('\n')
+		if line != "" {
+			entry := parser.ParseLogLine(strings.TrimRight(line, "\r\n"), 0)
+			entry.Source = r.name
+			batch = append(batch, entry)
+		}
+		if len(batch) > 0 && (in.Buffered() == 0 || len(batch) >= maxBatch || err != nil) {
+			emit(batch)
+			batch = nil
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			status(newStatus(r.name, "ERROR", err.Error()))
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,75 @@
+// Package source reads log entries from the inputs of gofly-cli: files,
+// gofly servers and streams. The UI consumes any of them the same way.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofly-cli/internal/loader"
+	"gofly-cli/internal/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source reads log entries from one input.
+type Source interface {
+	// Name identifies the source in entries and status events,
+	// e.g. a path or a server address
+	Name() string
+	// Run starts reading and blocks until the input ends or ctx is
+	// cancelled, which stops the source. emit gets the entries with Source
+	// set and Index left to the consumer, status reports the state of the
+	// source. Both may be called from several goroutines.
+	Run(ctx context.Context, emit func([]model.LogEntry), status func(Status)) error
+}
+
+// Status is an event about the state of a source.
+type Status struct {
+	Source string
+	Time   time.Time
+	// Level and Message describe connection changes and failures
+	Level   string
+	Message string
+	// Progress of loading a file, nil for other events
+	Progress *loader.Progress
+}
+
+func newStatus(source, level, message string) Status {
+	return Status{Source: source, Time: time.Now(), Level: level, Message: message}
+}
+
+// Line formats the status like a server log line.
+func (s Status) Line() string {
+	ts := s.Time.Format("2006-01-02 15:04:05")
+	return fmt.Sprintf("[%s] [%s] %s", ts, s.Level, s.Message)
+}
+
+// Multi runs several sources at once as one.
+type Multi []Source
+
+func (m Multi) Name() string {
+	names := make([]string, len(m))
+	for i, s := range m {
+		names[i] = s.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Run returns when all sources ended, with their errors joined.
+func (m Multi) Run(ctx context.Context, emit func([]model.LogEntry), status func(Status)) error {
+	errs := make([]error, len(m))
+
+	var wg sync.WaitGroup
+	for i, s := range m {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.Run(ctx, emit, status)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,163 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SUB renews the subscription, the server answers SUB_ACK
+	subInterval = 500 * time.Millisecond
+	// the server is lost when it doesn't acknowledge for this long
+	ackTimeout = time.Second
+)
+
+// UDP subscribes to a gofly server and receives the log lines it streams.
+type UDP struct {
+	Addr string
+
+	mu sync.Mutex
+	// time of the last SUB_ACK, the start until the first one arrives
+	lastAck   time.Time
+	connected bool
+	// the lost connection was reported
+	warned bool
+}
+
+func NewUDP(addr string) *UDP {
+	return &UDP{Addr: addr}
+}
+
+func (u *UDP) Name() string {
+	return u.Addr
+}
+
+// Connected reports whether the server acknowledged the last subscriptions.
+func (u *UDP) Connected() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.connected
+}
+
+// Run subscribes until ctx is cancelled, every received line is emitted as
+// an entry. Failures to set up the connection are reported in status too.
+func (u *UDP) Run(ctx context.Context, emit func([]model.LogEntry), status func(Status)) error {
+	err := u.run(ctx, emit, status)
+	if err != nil {
+		status(newStatus(u.Addr, "ERROR", err.Error()))
+	}
+	return err
+}
+
+func (u *UDP) run(ctx context.Context, emit func([]model.LogEntry), status func(Status)) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return fmt.Errorf("failed to create UDP listener: %w", err)
+	}
+	defer conn.Close()
+
+	server, err := net.ResolveUDPAddr("udp", u.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve server address %s: %w", u.Addr, err)
+	}
+
+	status(newStatus(u.Addr, "INFO", fmt.Sprintf("Connecting to %s", u.Addr)))
+	u.mu.Lock()
+	u.lastAck = time.Now()
+	u.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go u.subscribe(ctx, conn, server, status)
+
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				status(newStatus(u.Addr, "INFO", "UDP connection closed"))
+				return nil
+			}
+			status(newStatus(u.Addr, "ERROR", fmt.Sprintf("UDP read: %v", err)))
+			continue
+		}
+
+		msg := strings.TrimSpace(string(buf[:n]))
+		if strings.HasPrefix(msg, "SUB_ACK") {
+			if st, changed := u.ack(conn.LocalAddr()); changed {
+				status(st)
+			}
+			continue
+		}
+
+		entry := parser.ParseLogLine(msg, 0)
+		entry.Source = u.Addr
+		emit([]model.LogEntry{entry})
+	}
+}
+
+// ack records a SUB_ACK and returns a status event when it established or
+// restored the connection
+func (u *UDP) ack(local net.Addr) (Status, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.lastAck = time.Now()
+	if u.connected {
+		return Status{}, false
+	}
+	u.connected = true
+	if u.warned {
+		u.warned = false
+		return newStatus(u.Addr, "INFO", fmt.Sprintf("Reconnected to %s", u.Addr)), true
+	}
+	return newStatus(u.Addr, "INFO", fmt.Sprintf("Connected. Local: %s, Server: %s", local, u.Addr)), true
+}
+
+// subscribe sends SUB periodically and reports lost and restored connections
+func (u *UDP) subscribe(ctx context.Context, conn *net.UDPConn, server *net.UDPAddr, status func(Status)) {
+	ticker := time.NewTicker(subInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := conn.WriteToUDP([]byte("SUB"), server); err != nil && ctx.Err() == nil {
+			status(newStatus(u.Addr, "WARN", fmt.Sprintf("SUB send error: %v", err)))
+		}
+
+		if st, changed := u.check(); changed {
+			status(st)
+		}
+	}
+}
+
+// check marks the connection lost when no SUB_ACK arrived for ackTimeout and
+// returns a status event the first time. Only SUB_ACK connects again.
+func (u *UDP) check() (Status, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if time.Since(u.lastAck) <= ackTimeout || u.warned {
+		return Status{}, false
+	}
+	u.connected = false
+	u.warned = true
+	return newStatus(u.Addr, "WARN", fmt.Sprintf("Lost connection to %s", u.Addr)), true
+}
@@ -0,0 +1,149 @@
+package source
+
+import (
+	"context"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/server"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUDPStates(t *testing.T) {
+	local := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40000}
+	type step struct {
+		// ack a SUB_ACK, otherwise a tick of subscribe after the time
+		// since the last SUB_ACK
+		ack       bool
+		since     time.Duration
+		status    string
+		connected bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"first ack", []step{
+			{since: subInterval},
+			{ack: true, status: "Connected. Local: 127.0.0.1:40000", connected: true},
+			{ack: true, connected: true},
+			{since: subInterval, connected: true},
+		}},
+		{"server never answers", []step{
+			{since: subInterval},
+			{since: ackTimeout - 100*time.Millisecond},
+			{since: ackTimeout + time.Millisecond, status: "Lost connection"},
+			{since: 2 * ackTimeout},
+		}},
+		{"lost and restored", []step{
+			{ack: true, status: "Connected.", connected: true},
+			{since: ackTimeout + time.Millisecond, status: "Lost connection"},
+			{since: 2 * ackTimeout},
+			{ack: true, status: "Reconnected", connected: true},
+			{since: subInterval, connected: true},
+			{since: ackTimeout + time.Millisecond, status: "Lost connection"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUDP("127.0.0.1:9090")
+			u.lastAck = time.Now()
+
+			for i, s := range tt.steps {
+				var st Status
+				var changed bool
+				if s.ack {
+					st, changed = u.ack(local)
+				} else {
+					u.mu.Lock()
+					u.lastAck = time.Now().Add(-s.since)
+					u.mu.Unlock()
+					st, changed = u.check()
+				}
+
+				if changed != (s.status != "") || !strings.HasPrefix(st.Message, s.status) {
+					t.Errorf("step %d: status %q, %v, want %q", i, st.Message, changed, s.status)
+				}
+				if u.Connected() != s.connected {
+					t.Errorf("step %d: connected = %v, want %v", i, u.Connected(), s.connected)
+				}
+			}
+		})
+	}
+}
+
+func TestUDPRun(t *testing.T) {
+	srv, err := server.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.Addr().String()
+	srvCtx, stopServer := context.WithCancel(context.Background())
+	go srv.Run(srvCtx)
+
+	entries := make(chan model.LogEntry, 10)
+	statuses := make(chan Status, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	u := NewUDP(addr)
+	go func() {
+		done <- u.Run(ctx, func(batch []model.LogEntry) {
+			for _, e := range batch {
+				entries <- e
+			}
+		}, func(st Status) {
+			statuses <- st
+		})
+	}()
+
+	expect := func(prefix string) {
+		t.Helper()
+		for {
+			select {
+			case st := <-statuses:
+				if st.Source != addr {
+					t.Errorf("status of %q", st.Source)
+				}
+				if strings.HasPrefix(st.Message, prefix) {
+					return
+				}
+				if !strings.HasPrefix(st.Message, "Connecting") {
+					t.Fatalf("got status %q, want %q", st.Message, prefix)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no status %q", prefix)
+			}
+		}
+	}
+
+	expect("Connected.")
+	srv.Send("[2025-12-04 14:30:00] [WARN] retry")
+	select {
+	case e := <-entries:
+		if e.Level != "WARN" || e.Source != addr {
+			t.Errorf("got entry %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry")
+	}
+
+	stopServer()
+	expect("Lost connection")
+
+	srv, err = server.Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCtx, stopServer = context.WithCancel(context.Background())
+	defer stopServer()
+	go srv.Run(srvCtx)
+	expect("Reconnected")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	expect("UDP connection closed")
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"gofly-cli/internal/model"
+	"io"
+	"os"
+	"sync"
+)
+
+// Spill keeps evicted entries as JSON lines in a temporary file. It may be
+// searched while entries are added.
+type Spill struct {
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	count int
+	// bytes written, a search reads no further
+	size int64
+	err  error
+}
+
+// NewSpill creates the temporary file in the default temp directory.
+func NewSpill() (*Spill, error) {
+	file, err := os.CreateTemp("", "gofly-cli-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	return &Spill{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+func (s *Spill) Add(e model.LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err == nil {
+		data = append(data, '\n')
+		_, err = s.w.Write(data)
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.count++
+	s.size += int64(len(data))
+}
+
+// Len returns the number of spilled entries.
+func (s *Spill) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *Spill) Path() string {
+	return s.file.Name()
+}
+
+// Search returns up to limit spilled entries for which match is true,
+// oldest first. Entries added meanwhile are not searched.
+func (s *Spill) Search(match func(model.LogEntry) bool, limit int) ([]model.LogEntry, error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	err, size := s.err, s.size
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result []model.LogEntry
+	scanner := bufio.NewScanner(io.LimitReader(file, size))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e model.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return result, err
+		}
+		if match(e) {
+			result = append(result, e)
+			if len(result) >= limit {
+				break
+			}
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// Close removes the temporary file.
+func (s *Spill) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
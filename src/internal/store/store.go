@@ -0,0 +1,205 @@
+// Package store keeps log entries in memory with bounded retention.
+package store
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"strconv"
+	"strings"
+)
+
+// entryOverhead approximates the memory of a LogEntry besides its strings
+const entryOverhead = 160
+
+// Store is a ring buffer of log entries. Every entry keeps the position it
+// was appended at, so positions stay valid while the oldest entries are
+// evicted once MaxEntries or MaxBytes is exceeded.
+type Store struct {
+	// 0 means no limit
+	MaxEntries int
+	MaxBytes   int64
+
+	// OnEvict is called for every entry before it is dropped
+	OnEvict func(pos int, e model.LogEntry)
+
+	buf   []model.LogEntry
+	head  int
+	n     int
+	first int
+	bytes int64
+	spill *Spill
+}
+
+func New(maxEntries int, maxBytes int64) *Store {
+	return &Store{MaxEntries: maxEntries, MaxBytes: maxBytes}
+}
+
+// SetSpill keeps evicted entries in spill instead of dropping them.
+func (s *Store) SetSpill(spill *Spill) {
+	s.spill = spill
+}
+
+func (s *Store) Spill() *Spill {
+	return s.spill
+}
+
+// Append adds entries to the end and returns the number of evicted entries.
+// The first entry gets position Len().
+func (s *Store) Append(entries ...model.LogEntry) int {
+	evicted := 0
+	for _, e := range entries {
+		if s.n == len(s.buf) && !s.grow() {
+			evicted += s.evict(s.chunk())
+		}
+
+		s.buf[(s.head+s.n)%len(s.buf)] = e
+		s.n++
+		s.bytes += Size(e)
+
+		if s.MaxBytes > 0 && s.bytes > s.MaxBytes && s.n > 1 {
+			evicted += s.evict(s.chunk())
+		}
+	}
+	return evicted
+}
+
+// chunk is the number of entries evicted at once, so consumers that react
+// on evictions don't run for every appended entry
+func (s *Store) chunk() int {
+	chunk := s.n / 100
+	if chunk < 1 {
+		chunk = 1
+	}
+	return chunk
+}
+
+// grow enlarges the buffer unless MaxEntries is reached
+func (s *Store) grow() bool {
+	size := 2 * len(s.buf)
+	if size < 1024 {
+		size = 1024
+	}
+	if s.MaxEntries > 0 && size > s.MaxEntries {
+		size = s.MaxEntries
+	}
+	if size <= len(s.buf) {
+		return false
+	}
+
+	buf := make([]model.LogEntry, size)
+	for i := 0; i < s.n; i++ {
+		buf[i] = s.buf[(s.head+i)%len(s.buf)]
+	}
+	s.buf = buf
+	s.head = 0
+	return true
+}
+
+func (s *Store) evict(count int) int {
+	if count > s.n {
+		count = s.n
+	}
+
+	for i := 0; i < count; i++ {
+		e := s.buf[s.head]
+		if s.OnEvict != nil {
+			s.OnEvict(s.first, e)
+		}
+		if s.spill != nil {
+			s.spill.Add(e)
+		}
+
+		s.bytes -= Size(e)
+		// release the strings of the entry
+		s.buf[s.head] = model.LogEntry{}
+		s.head = (s.head + 1) % len(s.buf)
+		s.n--
+		s.first++
+	}
+
+	return count
+}
+
+// Skip leaves out the next n positions, as if n entries were appended and
+// evicted. Retained entries are evicted first, their number is returned.
+func (s *Store) Skip(n int) int {
+	evicted := s.evict(s.n)
+	s.first += n
+	return evicted
+}
+
+// Get returns the entry at pos, false when it was evicted or doesn't exist yet.
+func (s *Store) Get(pos int) (model.LogEntry, bool) {
+	if pos < s.first || pos >= s.first+s.n {
+		return model.LogEntry{}, false
+	}
+	return s.buf[(s.head+pos-s.first)%len(s.buf)], true
+}
+
+// First returns the position of the oldest retained entry.
+func (s *Store) First() int {
+	return s.first
+}
+
+// Len returns the position the next entry gets, the number of entries ever appended.
+func (s *Store) Len() int {
+	return s.first + s.n
+}
+
+// Retained returns the number of entries in memory.
+func (s *Store) Retained() int {
+	return s.n
+}
+
+// Dropped returns the number of evicted entries.
+func (s *Store) Dropped() int {
+	return s.first
+}
+
+// Bytes returns the approximate memory used by the retained entries.
+func (s *Store) Bytes() int64 {
+	return s.bytes
+}
+
+// Size approximates the memory used by e.
+func Size(e model.LogEntry) int64 {
+	return int64(entryOverhead + len(e.Index) + len(e.Timestamp) + len(e.Level) +
+		len(e.Message) + len(e.OriginalMessage) + len(e.CallID))
+}
+
+// LineSize approximates the memory used by the entry of a log line of n bytes.
+func LineSize(n int) int64 {
+	// the message is kept as logged and decoded
+	return entryOverhead + 2*int64(n)
+}
+
+// ParseSize parses sizes like "512MB", "2GB" or plain bytes.
+func ParseSize(text string) (int64, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if text == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		value  int64
+	}{
+		{"GB", 1 << 30}, {"G", 1 << 30},
+		{"MB", 1 << 20}, {"M", 1 << 20},
+		{"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			multiplier = unit.value
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	return int64(value * float64(multiplier)), nil
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"fmt"
+	"gofly-cli/internal/model"
+	"strings"
+	"testing"
+)
+
+func entry(i int) model.LogEntry {
+	// 40 bytes of message, Size is 200
+	return model.LogEntry{Message: fmt.Sprintf("%-40d", i)}
+}
+
+func messages(s *Store) []string {
+	var result []string
+	for pos := s.First(); pos < s.Len(); pos++ {
+		e, ok := s.Get(pos)
+		if !ok {
+			result = append(result, "missing")
+			continue
+		}
+		result = append(result, strings.TrimSpace(e.Message))
+	}
+	return result
+}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		maxBytes   int64
+		batches    []int
+		want       string
+		evicted    []int
+	}{
+		{"no limit", 0, 0, []int{3, 4}, "0 1 2 3 4 5 6", []int{0, 0}},
+		{"entries", 3, 0, []int{2, 2, 5}, "6 7 8", []int{0, 1, 5}},
+		{"ring wraps twice", 4, 0, []int{3, 3, 3, 3}, "8 9 10 11", []int{0, 2, 3, 3}},
+		{"bytes", 0, 500, []int{2, 1, 3}, "4 5", []int{0, 1, 3}},
+		{"bytes below one entry", 0, 100, []int{3}, "2", []int{2}},
+		{"entries before bytes", 2, 1000, []int{3}, "1 2", []int{1}},
+		{"bytes before entries", 4, 500, []int{4}, "2 3", []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.maxEntries, tt.maxBytes)
+			var evictedPos []int
+			s.OnEvict = func(pos int, e model.LogEntry) {
+				if want := fmt.Sprint(pos); strings.TrimSpace(e.Message) != want {
+					t.Errorf("evicted %q at position %d", e.Message, pos)
+				}
+				evictedPos = append(evictedPos, pos)
+			}
+
+			n := 0
+			for i, count := range tt.batches {
+				var batch []model.LogEntry
+				for range count {
+					batch = append(batch, entry(n))
+					n++
+				}
+				if got := s.Append(batch...); got != tt.evicted[i] {
+					t.Errorf("batch %d evicted %d, want %d", i, got, tt.evicted[i])
+				}
+			}
+
+			if got := strings.Join(messages(s), " "); got != tt.want {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+			if s.Len() != n || s.Dropped()+s.Retained() != n || s.Dropped() != len(evictedPos) {
+				t.Errorf("len %d, dropped %d, retained %d for %d entries and %d evictions",
+					s.Len(), s.Dropped(), s.Retained(), n, len(evictedPos))
+			}
+			for i, pos := range evictedPos {
+				if pos != i {
+					t.Errorf("eviction %d at position %d", i, pos)
+				}
+			}
+			if want := int64(s.Retained()) * Size(entry(0)); s.Bytes() != want {
+				t.Errorf("bytes = %d, want %d", s.Bytes(), want)
+			}
+			if _, ok := s.Get(s.First() - 1); ok && s.First() > 0 {
+				t.Errorf("evicted position %d still found", s.First()-1)
+			}
+			if _, ok := s.Get(s.Len()); ok {
+				t.Errorf("position %d found before it was appended", s.Len())
+			}
+		})
+	}
+}
+
+func TestSkip(t *testing.T) {
+	s := New(0, 0)
+	s.Append(entry(0), entry(1))
+	if evicted := s.Skip(3); evicted != 2 {
+		t.Errorf("skip evicted %d, want 2", evicted)
+	}
+	s.Append(entry(5), entry(6))
+
+	if s.First() != 5 || s.Len() != 7 || s.Bytes() != 2*Size(entry(0)) {
+		t.Errorf("first %d, len %d, bytes %d", s.First(), s.Len(), s.Bytes())
+	}
+	if got := strings.Join(messages(s), " "); got != "5 6" {
+		t.Errorf("entries = %q, want %q", got, "5 6")
+	}
+}
+
+func TestSpill(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	spill, err := NewSpill()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	s := New(2, 0)
+	s.SetSpill(spill)
+	for i := range 6 {
+		s.Append(entry(i))
+	}
+	if spill.Len() != 4 {
+		t.Fatalf("spilled %d entries, want 4", spill.Len())
+	}
+
+	odd, err := spill.Search(func(e model.LogEntry) bool {
+		return strings.TrimSpace(e.Message) == "1" || strings.TrimSpace(e.Message) == "3"
+	}, 10)
+	if err != nil || len(odd) != 2 || strings.TrimSpace(odd[1].Message) != "3" {
+		t.Errorf("search = %v, %v", odd, err)
+	}
+	if first, _ := spill.Search(func(model.LogEntry) bool { return true }, 1); len(first) != 1 {
+		t.Errorf("search with limit 1 returned %d entries", len(first))
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+		ok   bool
+	}{
+		{"", 0, true},
+		{"1024", 1024, true},
+		{"512MB", 512 << 20, true},
+		{"2g", 2 << 30, true},
+		{" 1.5 KB ", 1536, true},
+		{"10B", 10, true},
+		{"-1", 0, false},
+		{"MB", 0, false},
+		{"ten", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.text)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestSpillSearchWhileAdding(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	spill, err := NewSpill()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 2000 {
+			spill.Add(entry(i))
+		}
+	}()
+
+	last := 0
+	for searching := true; searching; {
+		select {
+		case <-done:
+			searching = false
+		default:
+		}
+		found, err := spill.Search(func(model.LogEntry) bool { return true }, 10000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every search sees complete entries in order, no fewer than before
+		for i, e := range found {
+			if strings.TrimSpace(e.Message) != fmt.Sprint(i) {
+				t.Fatalf("entry %d is %q", i, e.Message)
+			}
+		}
+		if len(found) < last {
+			t.Fatalf("found %d entries after %d", len(found), last)
+		}
+		last = len(found)
+	}
+
+	if found, _ := spill.Search(func(model.LogEntry) bool { return true }, 10000); len(found) != 2000 {
+		t.Errorf("found %d entries, want 2000", len(found))
+	}
+}
@@ -0,0 +1,78 @@
+package viewer
+
+import "github.com/gdamore/tcell/v2"
+
+var sessionPalette = []tcell.Color{
+	tcell.NewHexColor(0xFF6B6B), // Красный
+	tcell.NewHexColor(0xFFD166), // Желтый
+	tcell.NewHexColor(0x06D6A0), // Зеленый
+	tcell.NewHexColor(0x118AB2), // Синий
+	tcell.NewHexColor(0x9D4EDD), // Фиолетовый
+	tcell.NewHexColor(0x00BBF9), // Голубой
+	tcell.NewHexColor(0xFF9E6D), // Оранжевый
+}
+
+// SessionColors gives every Call-ID a color of the palette in turns,
+// in order of first use.
+type SessionColors struct {
+	colors map[string]tcell.Color
+	next   int
+}
+
+func (c *SessionColors) Get(callID string) tcell.Color {
+	if color, ok := c.colors[callID]; ok {
+		return color
+	}
+	if c.colors == nil {
+		c.colors = make(map[string]tcell.Color)
+	}
+
+	color := sessionPalette[c.next%len(sessionPalette)]
+	c.next++
+	c.colors[callID] = color
+
+	return color
+}
+
+// Remove forgets the colors of the calls, e.g. when they were dropped.
+func (c *SessionColors) Remove(callIDs []string) {
+	for _, id := range callIDs {
+		delete(c.colors, id)
+	}
+}
+
+// SessionColor returns the color of the call. Legs of one conversation get
+// shades of the color of its first leg, entries without a call gray.
+func (s *State) SessionColor(callID string) tcell.Color {
+	if callID == "" {
+		return tcell.ColorGray
+	}
+
+	if conversation := s.Calls.Conversation(callID); conversation != callID {
+		return legColor(s.SessionColor(conversation), s.Calls.Leg(callID))
+	}
+
+	return s.Colors.Get(callID)
+}
+
+// legColor lightens and darkens the conversation color in turns for further legs
+func legColor(color tcell.Color, leg int) tcell.Color {
+	if leg == 0 {
+		return color
+	}
+
+	target := 255.0
+	if leg%2 == 0 {
+		target = 0
+	}
+	ratio := 0.35 + 0.1*float64((leg-1)/2)
+	if ratio > 0.7 {
+		ratio = 0.7
+	}
+
+	r, g, b := color.RGB()
+	mix := func(c int32) int32 {
+		return int32(float64(c)*(1-ratio) + target*ratio)
+	}
+	return tcell.NewRGBColor(mix(r), mix(g), mix(b))
+}
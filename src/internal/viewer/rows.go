@@ -0,0 +1,206 @@
+package viewer
+
+import (
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"sort"
+)
+
+type RowKind uint8
+
+const (
+	MatchRow RowKind = iota
+	ContextRow
+	SeparatorRow
+)
+
+// Row is an entry of the store shown as a match, as context of a match,
+// or a separator between groups of them
+type Row struct {
+	Pos  int
+	Kind RowKind
+}
+
+// ContextEnabled reports whether rows around matches are shown.
+func (s *State) ContextEnabled() bool {
+	return s.Before > 0 || s.After > 0
+}
+
+// Rebuild recomputes the rows from the entries that pass the filters and
+// returns the number of matches.
+func (s *State) Rebuild() int {
+	s.Rows = s.Rows[:0]
+	s.SearchHits = s.SearchHits[:0]
+	s.Matched = 0
+	s.lastShown = -1
+	s.afterLeft = 0
+	s.Version++
+	s.Filters.Exclusions.ResetCounts()
+
+	if candidates, ok := s.candidates(); ok {
+		// other entries can only show as after-context of a match
+		next := s.Store.First()
+		for _, pos := range candidates {
+			for ; next < pos && s.afterLeft > 0; next++ {
+				s.show(next)
+			}
+			s.show(pos)
+			next = pos + 1
+		}
+		for ; next < s.Store.Len() && s.afterLeft > 0; next++ {
+			s.show(next)
+		}
+	} else {
+		for pos := s.Store.First(); pos < s.Store.Len(); pos++ {
+			s.show(pos)
+		}
+	}
+
+	return s.Matched
+}
+
+// candidates returns the positions of the entries that may match the
+// display filter, ok is false when they have to be scanned
+func (s *State) candidates() ([]int, bool) {
+	if s.Filters.Matcher == nil || s.opts.Stream || s.opts.NoText {
+		return nil, false
+	}
+	text, ok := s.Filters.Matcher.Text()
+	if !ok {
+		return nil, false
+	}
+	positions, ok := s.text.Search(text)
+	if !ok || s.file == nil {
+		return positions, ok
+	}
+
+	lines, ok := s.file.Search(text)
+	if !ok {
+		return nil, false
+	}
+	merged := make([]int, 0, len(positions)+len(lines))
+	for _, n := range lines {
+		pos := s.fileBase + n
+		if pos < s.Store.First() || pos >= s.Store.Len() {
+			continue
+		}
+		for len(positions) > 0 && positions[0] < pos {
+			merged = append(merged, positions[0])
+			positions = positions[1:]
+		}
+		merged = append(merged, pos)
+	}
+	return append(merged, positions...), true
+}
+
+// show adds the entry at pos if it passes the filters, preceded by its
+// before-context, or as after-context of an earlier match. Entries must be
+// passed in order.
+func (s *State) show(pos int) {
+	log, _ := s.Store.Get(pos)
+
+	if s.matches(log) {
+		start := pos - s.Before
+		if start <= s.lastShown {
+			start = s.lastShown + 1
+		}
+		if start < s.Store.First() {
+			start = s.Store.First()
+		}
+		if s.lastShown >= 0 && start > s.lastShown+1 && s.ContextEnabled() {
+			s.Rows = append(s.Rows, Row{s.lastShown, SeparatorRow})
+		}
+		for i := start; i < pos; i++ {
+			s.Rows = append(s.Rows, Row{i, ContextRow})
+		}
+
+		if s.Search != nil && s.Search.MatchEntry(log) {
+			s.SearchHits = append(s.SearchHits, len(s.Rows))
+		}
+		s.Rows = append(s.Rows, Row{pos, MatchRow})
+		s.Matched++
+		s.lastShown = pos
+		s.afterLeft = s.After
+		return
+	}
+
+	if s.afterLeft > 0 {
+		s.afterLeft--
+		s.lastShown = pos
+		s.Rows = append(s.Rows, Row{pos, ContextRow})
+	}
+}
+
+// matches reports whether log should be displayed. Entries that pass the
+// positive filters but are hidden by exclusions are added to their counters,
+// so it must be called once per entry per rebuild.
+func (s *State) matches(log model.LogEntry) bool {
+	return s.passes(log) && !s.Filters.Exclusions.Check(log)
+}
+
+// passes reports whether log passes the filters other than the exclusions
+func (s *State) passes(log model.LogEntry) bool {
+	f := &s.Filters
+	if !s.LevelVisible(log.Level) || !f.TimeRange.Contains(log.Time) {
+		return false
+	}
+	if len(f.Calls) > 0 && !f.Calls[log.CallID] {
+		return false
+	}
+	if f.Matcher != nil && !f.Matcher.MatchEntry(log) {
+		return false
+	}
+	return f.Expressions.Visible(log)
+}
+
+// LevelVisible reports whether entries of the level pass the level filters.
+func (s *State) LevelVisible(level string) bool {
+	if s.Filters.Hidden[level] {
+		return false
+	}
+	return s.Filters.MinSeverity == 0 || parser.Severity(level) >= s.Filters.MinSeverity
+}
+
+// dropRows removes the first n rows and returns n
+func (s *State) dropRows(n int) int {
+	if n == 0 {
+		return 0
+	}
+
+	for _, r := range s.Rows[:n] {
+		if r.Kind == MatchRow {
+			s.Matched--
+		}
+	}
+	s.Rows = append(s.Rows[:0], s.Rows[n:]...)
+	s.Version++
+
+	hits := s.SearchHits[:0]
+	for _, hit := range s.SearchHits {
+		if hit >= n {
+			hits = append(hits, hit-n)
+		}
+	}
+	s.SearchHits = hits
+
+	return n
+}
+
+// Entry returns the entry shown in row i, false for separators.
+func (s *State) Entry(i int) (model.LogEntry, bool) {
+	if i < 0 || i >= len(s.Rows) || s.Rows[i].Kind == SeparatorRow {
+		return model.LogEntry{}, false
+	}
+	return s.Store.Get(s.Rows[i].Pos)
+}
+
+// Find returns the index of the row showing the entry at pos or -1.
+func (s *State) Find(pos int) int {
+	i := sort.Search(len(s.Rows), func(i int) bool { return s.Rows[i].Pos >= pos })
+	for ; i < len(s.Rows) && s.Rows[i].Pos == pos; i++ {
+		if s.Rows[i].Kind != SeparatorRow {
+			return i
+		}
+	}
+	return -1
+}
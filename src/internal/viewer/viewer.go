@@ -0,0 +1,331 @@
+// Package viewer is the headless core of gofly-cli: it stores log entries,
+// keeps counters and indexes of them and decides which of them are displayed.
+// The UI only renders its state.
+package viewer
+
+import (
+	"fmt"
+	"gofly-cli/internal/calls"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/store"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options configure the retention of the entries.
+type Options struct {
+	// 0 means no limit, MaxBytes bounds the entries and their text index
+	MaxEntries int
+	MaxBytes   int64
+	// keep evicted entries in a temporary file
+	Spill bool
+	// entries are only shown once, as they are added: calls are not
+	// tracked and the text is not indexed for rebuilds
+	Stream bool
+	// calls are tracked but the text is not indexed, text filters scan
+	// the entries
+	NoText bool
+}
+
+// Viewer guards a State, its methods are safe for concurrent use.
+type Viewer struct {
+	mu sync.Mutex
+	s  *State
+}
+
+func New(opts Options) (*Viewer, error) {
+	s, err := newState(opts)
+	return &Viewer{s: s}, err
+}
+
+// Do runs fn with exclusive access to the state. fn must not call methods
+// of the Viewer.
+func (v *Viewer) Do(fn func(s *State)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fn(v.s)
+}
+
+// AddResult tells the UI how the rows changed when entries were added.
+type AddResult struct {
+	// rows removed from the top with evicted entries
+	Removed int
+	// rows appended
+	Shown int
+}
+
+// Add appends entries to the store, numbering them by their position, and
+// shows those passing the filters.
+func (v *Viewer) Add(entries []model.LogEntry) AddResult {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.s.add(entries)
+}
+
+// Get returns the entry at pos, so the Viewer can be passed as model.Entries.
+func (v *Viewer) Get(pos int) (model.LogEntry, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.s.Store.Get(pos)
+}
+
+// Clear drops all entries and their counters. The display filter and
+// the call filter are reset, other filters are kept.
+func (v *Viewer) Clear() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.s.close()
+	s, err := newState(v.s.opts)
+	s.Filters = v.s.Filters
+	s.Filters.Text = ""
+	s.Filters.Matcher = nil
+	s.Filters.Calls = make(map[string]bool)
+	s.Filters.Expressions.ResetHits()
+	s.Filters.Exclusions.ResetCounts()
+	s.Before, s.After = v.s.Before, v.s.After
+	s.Search = v.s.Search
+	s.Colors = v.s.Colors
+	// cached renderings of the old rows must not be reused
+	s.Version = v.s.Version + 1
+	v.s = s
+	return err
+}
+
+// Close removes the spill file.
+func (v *Viewer) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.s.close()
+}
+
+// Filters decide which entries are displayed.
+type Filters struct {
+	// display filter, nil shows every entry
+	Text    string
+	Matcher *filter.Matcher
+	Hidden  map[string]bool
+	// entries below MinSeverity are hidden, 0 shows everything
+	MinSeverity int
+	TimeRange   filter.TimeRange
+	// when not empty only entries of these calls are displayed
+	Calls       map[string]bool
+	Expressions filter.ExpressionList
+	Exclusions  filter.ExclusionList
+}
+
+// State holds the entries and everything derived from them. It is not safe
+// for concurrent use, a Viewer guards it.
+type State struct {
+	Store   *store.Store
+	Calls   *calls.Tracker
+	Filters Filters
+	// grep-like -B/-A context around filter matches
+	Before, After int
+	// search highlights and navigates matches without hiding rows
+	Search *filter.Matcher
+
+	Colors SessionColors
+
+	// entries per level, dropped ones excluded
+	LevelCounts map[string]int
+	// entries added, dropped ones included
+	Added int
+
+	Rows []Row
+	// indexes of the rows matching Search, ascending
+	SearchHits []int
+	// rows that matched the filters, without context rows
+	Matched int
+	// changes whenever existing rows change
+	Version int
+
+	opts Options
+	// tokens of the stored entries, narrows down text filters
+	text *index.Memory
+	// sidecar index of a loaded file, its line n is at position fileBase+n.
+	// The text index leaves its lines to it.
+	file     *index.Index
+	fileBase int
+	// entries before this position are in the level and expression counters
+	accounted int
+	// position of the last entry added to Rows
+	lastShown int
+	// after-context rows still to show for the last match
+	afterLeft int
+	// Calls.Relinked when Version was last bumped for it
+	relinked int
+}
+
+func newState(opts Options) (*State, error) {
+	s := &State{
+		Store:       store.New(opts.MaxEntries, opts.MaxBytes),
+		Calls:       calls.NewTracker(),
+		LevelCounts: make(map[string]int),
+		Filters: Filters{
+			Hidden: make(map[string]bool),
+			Calls:  make(map[string]bool),
+		},
+		opts:      opts,
+		text:      index.NewMemory(),
+		lastShown: -1,
+	}
+	s.Store.OnEvict = func(pos int, e model.LogEntry) {
+		// a large batch may evict its own entries before they are accounted
+		if pos < s.accounted {
+			s.LevelCounts[e.Level]--
+			s.Filters.Expressions.Uncount(e)
+			if s.passes(e) {
+				s.Filters.Exclusions.Uncount(e)
+			}
+		}
+	}
+
+	if opts.Spill {
+		spill, err := store.NewSpill()
+		if err != nil {
+			return s, fmt.Errorf("failed to create spill file: %w", err)
+		}
+		s.Store.SetSpill(spill)
+	}
+
+	return s, nil
+}
+
+func (s *State) close() {
+	if spill := s.Store.Spill(); spill != nil {
+		spill.Close()
+	}
+}
+
+func (s *State) add(entries []model.LogEntry) AddResult {
+	var result AddResult
+	if len(entries) == 0 {
+		return result
+	}
+
+	start := s.Store.Len()
+	for i := range entries {
+		entries[i].Index = strconv.Itoa(start + i)
+	}
+	// the text index shares the memory budget with the entries, which keep
+	// at least a quarter of it
+	if s.opts.MaxBytes > 0 {
+		s.Store.MaxBytes = max(s.opts.MaxBytes-s.text.Bytes(), s.opts.MaxBytes/4)
+	}
+	if s.Store.Append(entries...) > 0 {
+		result.Removed = s.dropEvicted()
+		start = max(start, s.Store.First())
+	}
+	s.Added += len(entries)
+
+	var newest time.Time
+	for _, e := range entries {
+		if e.Time.After(newest) {
+			newest = e.Time
+		}
+	}
+	if s.Filters.TimeRange.Slide(newest) {
+		result.Removed += s.dropRowsOutside()
+	}
+
+	rows := len(s.Rows)
+	for pos := start; pos < s.Store.Len(); pos++ {
+		s.account(pos)
+		s.show(pos)
+	}
+	result.Shown = len(s.Rows) - rows
+
+	// rows already shown change color when their calls are linked
+	if relinked := s.Calls.Relinked(); relinked != s.relinked {
+		s.relinked = relinked
+		s.Version++
+	}
+
+	return result
+}
+
+// account updates the counters and indexes kept for the entry at pos
+func (s *State) account(pos int) {
+	log, _ := s.Store.Get(pos)
+
+	s.LevelCounts[log.Level]++
+	s.Filters.Expressions.Count(log)
+	if !s.opts.Stream {
+		s.Calls.Add(pos, log)
+		if !s.opts.NoText && !s.inFile(pos) {
+			s.text.Add(pos, log)
+		}
+	}
+	s.accounted = pos + 1
+}
+
+// UseFileIndex is called before the lines of a file with the sidecar index
+// idx are added. Text filters search idx for them instead of indexing them
+// again. The first skipped lines are not added, they count as dropped.
+func (s *State) UseFileIndex(idx *index.Index, skipped int) {
+	s.file = idx
+	s.fileBase = s.Store.Len()
+	if skipped > 0 {
+		s.Store.Skip(skipped)
+		s.dropEvicted()
+		s.Added += skipped
+	}
+}
+
+func (s *State) inFile(pos int) bool {
+	return s.file != nil && pos >= s.fileBase && pos < s.fileBase+s.file.Lines()
+}
+
+// dropEvicted forgets rows and calls of the entries evicted from the store
+// and returns the number of removed rows
+func (s *State) dropEvicted() int {
+	first := s.Store.First()
+	s.Colors.Remove(s.Calls.Drop(first))
+	s.text.Drop(first)
+
+	return s.dropRows(sort.Search(len(s.Rows), func(i int) bool { return s.Rows[i].Pos >= first }))
+}
+
+// dropRowsOutside removes the rows before the first match still inside a
+// sliding time range, keeping its before-context, and returns their number
+func (s *State) dropRowsOutside() int {
+	n := len(s.Rows)
+	for i, r := range s.Rows {
+		if r.Kind != MatchRow {
+			continue
+		}
+		if log, _ := s.Store.Get(r.Pos); s.Filters.TimeRange.Contains(log.Time) {
+			n = i
+			break
+		}
+	}
+	for n > 0 && n < len(s.Rows) && s.Rows[n-1].Kind == ContextRow {
+		n--
+	}
+	return s.dropRows(n)
+}
+
+// RecountExpressions recounts the hits of the match expressions.
+func (s *State) RecountExpressions() {
+	s.Filters.Expressions.ResetHits()
+	for pos := s.Store.First(); pos < s.Store.Len(); pos++ {
+		log, _ := s.Store.Get(pos)
+		s.Filters.Expressions.Count(log)
+	}
+}
+
+// Latest returns the newest time of the stored entries, zero without any.
+func (s *State) Latest() time.Time {
+	var latest time.Time
+	for pos := s.Store.First(); pos < s.Store.Len(); pos++ {
+		if log, _ := s.Store.Get(pos); log.Time.After(latest) {
+			latest = log.Time
+		}
+	}
+	return latest
+}
@@ -0,0 +1,443 @@
+package viewer
+
+import (
+	"fmt"
+	"gofly-cli/internal/filter"
+	"gofly-cli/internal/index"
+	"gofly-cli/internal/model"
+	"gofly-cli/internal/parser"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// entries builds one entry per message, a message "level:text" sets the level
+func entries(messages ...string) []model.LogEntry {
+	result := make([]model.LogEntry, len(messages))
+	for i, msg := range messages {
+		level := "INFO"
+		if l, text, ok := strings.Cut(msg, ":"); ok {
+			level, msg = l, text
+		}
+		result[i] = model.LogEntry{Level: level, Message: msg, OriginalMessage: msg}
+	}
+	return result
+}
+
+// rowsText writes rows as "2 3c - 7": positions, c for context, - for separators
+func rowsText(rows []Row) string {
+	parts := make([]string, len(rows))
+	for i, r := range rows {
+		switch r.Kind {
+		case MatchRow:
+			parts[i] = fmt.Sprint(r.Pos)
+		case ContextRow:
+			parts[i] = fmt.Sprintf("%dc", r.Pos)
+		case SeparatorRow:
+			parts[i] = "-"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func mustCompile(t *testing.T, expr string) *filter.Matcher {
+	t.Helper()
+	m, err := filter.Compile(expr, filter.ModeText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRebuild(t *testing.T) {
+	log := entries("a", "b", "hit c", "d", "hit e", "f", "g", "h", "hit i", "j")
+
+	tests := []struct {
+		name          string
+		filter        string
+		before, after int
+		want          string
+		matched       int
+	}{
+		{"no filter", "", 0, 0, "0 1 2 3 4 5 6 7 8 9", 10},
+		{"filter", "hit", 0, 0, "2 4 8", 3},
+		{"no match", "nothing", 0, 0, "", 0},
+		{"before", "hit", 2, 0, "0c 1c 2 3c 4 - 6c 7c 8", 3},
+		{"after", "hit", 0, 2, "2 3c 4 5c 6c - 8 9c", 3},
+		{"around", "hit", 1, 1, "1c 2 3c 4 5c - 7c 8 9c", 3},
+		{"context past the ends", "hit", 5, 5, "0c 1c 2 3c 4 5c 6c 7c 8 9c", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := New(Options{})
+			var added []Row
+			v.Do(func(s *State) {
+				if tt.filter != "" {
+					s.Filters.Matcher = mustCompile(t, tt.filter)
+				}
+				s.Before, s.After = tt.before, tt.after
+			})
+			// entries added one by one show the same rows as a rebuild
+			for _, e := range log {
+				v.Add([]model.LogEntry{e})
+			}
+			v.Do(func(s *State) {
+				added = append(added, s.Rows...)
+				s.Rebuild()
+			})
+
+			v.Do(func(s *State) {
+				if got := rowsText(s.Rows); got != tt.want {
+					t.Errorf("rows = %q, want %q", got, tt.want)
+				}
+				if got := rowsText(added); got != tt.want {
+					t.Errorf("added rows = %q, want %q", got, tt.want)
+				}
+				if s.Matched != tt.matched {
+					t.Errorf("matched = %d, want %d", s.Matched, tt.matched)
+				}
+			})
+		})
+	}
+}
+
+func TestRebuildFilters(t *testing.T) {
+	log := entries("DEBUG:a", "INFO:b", "WARN:c", "ERROR:d", "INFO:noise e", "ERROR:noise f")
+
+	tests := []struct {
+		name   string
+		set    func(s *State)
+		want   string
+		hidden int
+	}{
+		{"hidden level", func(s *State) { s.Filters.Hidden["INFO"] = true }, "0 2 3 5", 0},
+		{"min level", func(s *State) { s.Filters.MinSeverity = 30 }, "2 3 5", 0},
+		{"exclusion", func(s *State) { s.Filters.Exclusions.Add(mustCompile(t, "noise")) }, "0 1 2 3", 2},
+		{"exclusion below min level", func(s *State) {
+			s.Filters.MinSeverity = 40
+			s.Filters.Exclusions.Add(mustCompile(t, "noise"))
+		}, "3", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := New(Options{})
+			v.Do(func(s *State) {
+				tt.set(s)
+			})
+			v.Add(append([]model.LogEntry(nil), log...))
+			v.Do(func(s *State) {
+				s.Rebuild()
+				if got := rowsText(s.Rows); got != tt.want {
+					t.Errorf("rows = %q, want %q", got, tt.want)
+				}
+				if s.Filters.Exclusions.Hidden != tt.hidden {
+					t.Errorf("hidden = %d, want %d", s.Filters.Exclusions.Hidden, tt.hidden)
+				}
+			})
+		})
+	}
+}
+
+func TestEviction(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		filter     string
+		search     string
+		after      int
+		batches    [][]string
+		want       string
+		removed    int
+		hits       []int
+		levels     map[string]int
+		suppressed int
+	}{
+		{
+			name:       "rows of evicted entries",
+			maxEntries: 4,
+			batches:    [][]string{{"a", "b", "c", "d"}, {"e", "f"}},
+			want:       "2 3 4 5",
+			removed:    2,
+			levels:     map[string]int{"INFO": 4},
+		},
+		{
+			name:       "search hits move up",
+			maxEntries: 4,
+			search:     "x",
+			batches:    [][]string{{"x a", "b", "x c", "d"}, {"x e"}},
+			want:       "1 2 3 4",
+			removed:    1,
+			hits:       []int{1, 3},
+			levels:     map[string]int{"INFO": 4},
+		},
+		{
+			name:       "level counts",
+			maxEntries: 3,
+			batches:    [][]string{{"ERROR:a", "WARN:b"}, {"INFO:c", "INFO:d"}, {"DEBUG:e"}},
+			want:       "2 3 4",
+			removed:    1,
+			levels:     map[string]int{"ERROR": 0, "WARN": 0, "INFO": 2, "DEBUG": 1},
+		},
+		{
+			name:       "a batch evicting its own entries",
+			maxEntries: 2,
+			batches:    [][]string{{"ERROR:a", "WARN:b", "INFO:c", "INFO:d", "DEBUG:e"}},
+			want:       "3 4",
+			levels:     map[string]int{"ERROR": 0, "WARN": 0, "INFO": 1, "DEBUG": 1},
+		},
+		{
+			name:       "context of evicted matches",
+			maxEntries: 4,
+			filter:     "hit",
+			after:      1,
+			batches:    [][]string{{"hit a", "b", "c", "hit d"}, {"e", "f"}},
+			want:       "3 4c",
+			removed:    3,
+			levels:     map[string]int{"INFO": 4},
+		},
+		{
+			name:       "exclusion counts",
+			maxEntries: 3,
+			batches:    [][]string{{"noise a", "noise b", "c"}, {"d", "noise e"}},
+			want:       "2 3",
+			removed:    0,
+			levels:     map[string]int{"INFO": 3},
+			suppressed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := New(Options{MaxEntries: tt.maxEntries})
+			v.Do(func(s *State) {
+				if tt.filter != "" {
+					s.Filters.Matcher = mustCompile(t, tt.filter)
+				}
+				if tt.search != "" {
+					s.Search = mustCompile(t, tt.search)
+				}
+				s.After = tt.after
+				s.Filters.Exclusions.Add(mustCompile(t, "noise"))
+			})
+
+			var result AddResult
+			for _, batch := range tt.batches {
+				result = v.Add(entries(batch...))
+			}
+
+			v.Do(func(s *State) {
+				if got := rowsText(s.Rows); got != tt.want {
+					t.Errorf("rows = %q, want %q", got, tt.want)
+				}
+				if result.Removed != tt.removed {
+					t.Errorf("removed = %d, want %d", result.Removed, tt.removed)
+				}
+				if !slices.Equal(s.SearchHits, tt.hits) {
+					t.Errorf("search hits = %v, want %v", s.SearchHits, tt.hits)
+				}
+				for level, n := range tt.levels {
+					if s.LevelCounts[level] != n {
+						t.Errorf("%s count = %d, want %d", level, s.LevelCounts[level], n)
+					}
+				}
+				if got := s.Filters.Exclusions.Items[0].Suppressed; got != tt.suppressed {
+					t.Errorf("suppressed = %d, want %d", got, tt.suppressed)
+				}
+				if got := s.Filters.Exclusions.Hidden; got != tt.suppressed {
+					t.Errorf("hidden = %d, want %d", got, tt.suppressed)
+				}
+
+				// counters kept while evicting match a rebuild
+				matched := s.Matched
+				if s.Rebuild(); s.Matched != matched || rowsText(s.Rows) != tt.want {
+					t.Errorf("rebuild gives %q with %d matches, kept %q with %d", rowsText(s.Rows), s.Matched, tt.want, matched)
+				}
+			})
+		})
+	}
+}
+
+func TestSlidingTimeRange(t *testing.T) {
+	start := time.Date(2025, 12, 4, 14, 30, 0, 0, time.UTC)
+	r, err := filter.ParseTimeRange("last 2s", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := New(Options{})
+	v.Do(func(s *State) {
+		s.Filters.TimeRange = r
+	})
+
+	var removed int
+	for i := range 5 {
+		e := entries("m")
+		e[0].Time = start.Add(time.Duration(i) * time.Second)
+		removed += v.Add(e).Removed
+	}
+
+	v.Do(func(s *State) {
+		if got, want := rowsText(s.Rows), "2 3 4"; got != want {
+			t.Errorf("rows = %q, want %q", got, want)
+		}
+		if removed != 2 || s.Matched != 3 {
+			t.Errorf("removed %d rows with %d matches left, want 2 and 3", removed, s.Matched)
+		}
+	})
+}
+
+func TestUseFileIndex(t *testing.T) {
+	log := entries("invite a", "b", "bye c", "invite d", "e", "invite f")
+
+	chunk := index.NewChunk()
+	for i, e := range log {
+		chunk.Add(int64(i), e)
+	}
+	builder := index.NewBuilder(index.Key{})
+	builder.Add(chunk)
+	idx := builder.Index()
+
+	tests := []struct {
+		name    string
+		skipped int
+		filter  string
+		want    string
+	}{
+		{"all lines", 0, "invite", "0 3 5"},
+		{"skipped lines", 2, "invite", "3 5"},
+		{"after the file", 0, "later", "6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := New(Options{})
+			v.Do(func(s *State) {
+				s.UseFileIndex(idx, tt.skipped)
+			})
+			v.Add(append([]model.LogEntry(nil), log[tt.skipped:]...))
+			v.Add(entries("later g"))
+
+			v.Do(func(s *State) {
+				s.Filters.Matcher = mustCompile(t, tt.filter)
+				s.Rebuild()
+				if got := rowsText(s.Rows); got != tt.want {
+					t.Errorf("rows = %q, want %q", got, tt.want)
+				}
+				if s.Store.First() != tt.skipped {
+					t.Errorf("first = %d, want %d", s.Store.First(), tt.skipped)
+				}
+			})
+		})
+	}
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	v, _ := New(Options{MaxEntries: 100})
+	v.Do(func(s *State) {
+		s.Filters.Matcher = mustCompile(t, "hit")
+	})
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				v.Add(entries("hit", "miss"))
+				v.Do(func(s *State) { s.Rebuild() })
+			}
+		}()
+	}
+	wg.Wait()
+
+	v.Do(func(s *State) {
+		if s.Added != 800 || s.Store.Retained() != 100 || s.Matched != 50 {
+			t.Errorf("added %d, retained %d, matched %d", s.Added, s.Store.Retained(), s.Matched)
+		}
+	})
+}
+
+func TestRelinkedCallsChangeVersion(t *testing.T) {
+	invite := func(callID string) model.LogEntry {
+		return parser.ParseLogLine(`[2025-12-04 15:00:00] [INFO] INVITE sip:74950001111@10.0.0.9 SIP/2.0\r\n`+
+			`From: <sip:79001234567@10.0.0.1>;tag=1\r\nTo: <sip:74950001111@10.0.0.9>\r\nCall-ID: `+callID+
+			`\r\nCSeq: 1 INVITE\r\n`, 0)
+	}
+
+	v, _ := New(Options{})
+	v.Add([]model.LogEntry{invite("leg-a")})
+	var version int
+	var color tcell.Color
+	v.Do(func(s *State) {
+		version = s.Version
+		color = s.SessionColor("leg-a")
+	})
+
+	// the second leg joins the conversation of the first, the rows of both
+	// are rendered again
+	v.Add([]model.LogEntry{invite("leg-b")})
+	v.Do(func(s *State) {
+		if s.Version == version {
+			t.Error("linking a leg kept the version of the rows")
+		}
+		if s.Calls.Conversation("leg-b") != "leg-a" || s.SessionColor("leg-a") != color {
+			t.Errorf("leg-b is in conversation %s", s.Calls.Conversation("leg-b"))
+		}
+		version = s.Version
+	})
+
+	v.Add([]model.LogEntry{{Message: "unrelated"}})
+	v.Do(func(s *State) {
+		if s.Version != version {
+			t.Error("an entry without a call changed the version of the rows")
+		}
+	})
+}
+
+func TestEvictionFreesCallsAndIndex(t *testing.T) {
+	const maxBytes = 2 << 20
+	v, _ := New(Options{MaxBytes: maxBytes})
+
+	var most int64
+	for i := range 60000 {
+		id := fmt.Sprintf("%08x%08x@10.0.0.%d", i*7919, i*104729, i%256)
+		e := model.LogEntry{CallID: id, Message: "BYE Call-ID: " + id, OriginalMessage: "BYE Call-ID: " + id}
+		v.Add([]model.LogEntry{e})
+		v.Do(func(s *State) {
+			s.SessionColor(id)
+			most = max(most, s.Store.Bytes()+s.text.Bytes())
+		})
+	}
+
+	v.Do(func(s *State) {
+		// the index of the latest batch counts from the next one on
+		if most > maxBytes+maxBytes/100 {
+			t.Errorf("entries and text index used %d bytes, more than %d", most, maxBytes)
+		}
+		if s.Store.Retained() < 100 {
+			t.Errorf("only %d entries retained", s.Store.Retained())
+		}
+		if len(s.Colors.colors) > s.Calls.Len() {
+			t.Errorf("%d colors kept for %d calls", len(s.Colors.colors), s.Calls.Len())
+		}
+	})
+}
+
+func TestNoText(t *testing.T) {
+	v, _ := New(Options{MaxEntries: 3, NoText: true})
+	v.Add(entries("hit a", "b", "hit c", "d", "hit e"))
+	v.Do(func(s *State) {
+		if s.text.Bytes() != 0 {
+			t.Errorf("text indexed, %d bytes", s.text.Bytes())
+		}
+
+		// text filters scan the entries instead
+		s.Filters.Matcher = mustCompile(t, "hit")
+		s.Rebuild()
+		if got := rowsText(s.Rows); got != "2 4" {
+			t.Errorf("rows = %q, want %q", got, "2 4")
+		}
+	})
+}